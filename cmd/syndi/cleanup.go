@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/manifest"
+)
+
+func cleanupCmd(argv []string) {
+	args := config.RunArgs{}
+	var dir string
+	var batch int
+	var dryRun bool
+	fs := newFlagSet("syndi cleanup", "syndi cleanup [flags] [run-id]")
+	connectionFlags(fs, &args.Connection)
+	fs.StringVar(&args.ConfigFile, "config", "", "Run config file with a Connection section")
+	fs.StringVar(&args.OptionFile, "defaults-file", "", "MySQL option file to read [client] and [syndi] groups from (default: ~/.my.cnf)")
+	fs.StringVar(&dir, "manifest-dir", manifest.DefaultDir, "Directory with manifests of the runs")
+	fs.IntVar(&batch, "batch", 1000, "Maximum number of rows deleted by a single statement")
+	fs.BoolVar(&dryRun, "dry-run", false, "Print the DELETE statements instead of executing them")
+	runIDs := parseInterspersed(fs, argv)
+	if len(runIDs) == 0 {
+		listRuns(dir)
+		return
+	}
+	if len(runIDs) > 1 || batch <= 0 {
+		fs.Usage()
+		log.Fatal("exactly one run ID and a positive -batch are expected")
+	}
+
+	m, err := manifest.Load(dir, runIDs[0])
+	if err != nil {
+		log.Panic(err)
+	}
+	var db *sql.DB
+	if !dryRun {
+		dsn, err := args.GetDSN()
+		if err != nil {
+			log.Panicf("error loading config: %#v:", err)
+		}
+		db = openDB(dsn)
+		defer db.Close()
+	}
+
+	// Tables are deleted from in reverse order, so that rows referencing others go first.
+	for i := len(m.Tables) - 1; i >= 0; i-- {
+		t := m.Tables[i]
+		var deleted int64
+		err = t.Deletes(batch, func(stmt string) error {
+			if dryRun {
+				fmt.Println(stmt + ";")
+				return nil
+			}
+			res, err := db.Exec(stmt)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			deleted += n
+			return err
+		})
+		if err != nil {
+			log.Panic(err)
+		}
+		if !dryRun {
+			log.Printf("deleted %d of %d rows inserted into %s", deleted, t.Rows(), t.Name)
+		}
+	}
+	if !dryRun {
+		err = manifest.Remove(dir, m.RunID)
+		if err != nil {
+			log.Panic(err)
+		}
+		log.Printf("run %s cleaned up", m.RunID)
+	}
+}
+
+// listRuns prints the runs that can be cleaned up.
+func listRuns(dir string) {
+	ids, err := manifest.List(dir)
+	if err != nil {
+		log.Panic(err)
+	}
+	if len(ids) == 0 {
+		fmt.Printf("no runs to clean up in %s\n", dir)
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "run ID\tcreated\ttables\trows")
+	for _, id := range ids {
+		m, err := manifest.Load(dir, id)
+		if err != nil {
+			log.Panic(err)
+		}
+		var rows int64
+		for _, t := range m.Tables {
+			rows += t.Rows()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", m.RunID, m.Created.Format("2006-01-02 15:04:05"), len(m.Tables), rows)
+	}
+	_ = tw.Flush()
+}
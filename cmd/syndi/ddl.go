@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/schema"
+)
+
+func ddlCmd(argv []string) {
+	var vars config.Vars
+	var dialectName string
+	fs := newFlagSet("syndi ddl", "syndi ddl [flags] <table.yaml>...")
+	fs.StringVar(&dialectName, "dialect", schema.MySQL.Name, "SQL dialect of the statements: mysql or postgres")
+	fs.Var(&vars, "var", "Variable used in table definitions as ${key}, given as key=value (can be repeated)")
+	_ = fs.Parse(argv)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		log.Fatal("at least one table definition is expected")
+	}
+	dialect, err := schema.GetDialect(dialectName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, path := range fs.Args() {
+		tdef, err := config.LoadTableDef(path, vars)
+		if err != nil {
+			log.Panic(err)
+		}
+		ddl, err := dialect.CreateTable(tdef)
+		if err != nil {
+			log.Panicf("%s: %s", path, err)
+		}
+		fmt.Printf("%s;\n\n", ddl)
+	}
+}
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/generators"
+	"github.com/bitstonks/syndi/internal/importer"
+	"github.com/bitstonks/syndi/internal/manifest"
+	"github.com/bitstonks/syndi/internal/metrics"
+	"github.com/bitstonks/syndi/internal/progress"
+	"github.com/bitstonks/syndi/internal/schema"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// connectionFlags registers flags for database connection settings.
+func connectionFlags(fs *flag.FlagSet, conn *config.Connection) {
+	fs.StringVar(&conn.DSN, "dsn", "", "Database DSN, overrides other connection flags (env: SYNDI_DSN)")
+	fs.StringVar(&conn.Database, "db", "", "Database name to use")
+	fs.StringVar(&conn.Host, "host", "", "Database host to connect to (env: MYSQL_HOST, default: localhost)")
+	fs.StringVar(&conn.Password, "p", "", "Database user's password, prefer MYSQL_PWD or an option file")
+	fs.StringVar(&conn.Port, "P", "", "Database port number (env: MYSQL_TCP_PORT, default: 3306)")
+	fs.StringVar(&conn.Socket, "socket", "", "Unix socket file to connect to instead of TCP (env: MYSQL_UNIX_PORT)")
+	fs.StringVar(&conn.User, "u", "", "Database user (default: root)")
+	fs.StringVar(&conn.SSLCA, "ssl-ca", "", "File with the CA certificate(s) used to verify the server")
+	fs.StringVar(&conn.SSLCert, "ssl-cert", "", "File with the client TLS certificate")
+	fs.StringVar(&conn.SSLKey, "ssl-key", "", "File with the client TLS key")
+}
+
+// runArgsFlags registers flags shared by commands that load table definitions.
+func runArgsFlags(fs *flag.FlagSet, args *config.RunArgs) {
+	connectionFlags(fs, &args.Connection)
+	fs.StringVar(&args.OptionFile, "defaults-file", "", "MySQL option file to read [client] and [syndi] groups from (default: ~/.my.cnf)")
+	fs.Var(&args.Safe, "safe", "Whether foreign key checks are mandated (default: Safe of the run config file or false)")
+	fs.Float64Var(&args.Scale, "scale", 0, "Factor to multiply TotalRecords of all tables with (unless overridden by ScaleWith)")
+	fs.Int64Var(&args.Seed, "seed", 0, "Seed for reproducible data, 0 means random")
+	fs.Var(&args.Vars, "var", "Variable used in table definitions as ${key}, given as key=value (can be repeated)")
+}
+
+// importOptions control the import itself rather than what is imported.
+type importOptions struct {
+	skipPreflight bool
+	createTables  bool
+	progress      string
+	quiet         bool
+	metricsAddr   string
+	manifestDir   string
+	rate          string
+	load          importer.LoadOptions
+}
+
+// progressInterval is how often the progress is reported.
+const progressInterval = 2 * time.Second
+
+// manifestInterval is how often keys of the inserted rows are appended to the manifest.
+const manifestInterval = time.Second
+
+func importOptionsFlags(fs *flag.FlagSet, opts *importOptions) {
+	fs.BoolVar(&opts.skipPreflight, "skip-preflight", false, "Don't check table definitions against the table schemas")
+	fs.BoolVar(&opts.createTables, "create-tables", false, "Create missing tables with columns inferred from the generators")
+	fs.StringVar(&opts.progress, "progress", progress.Text, "How to report progress: text, json or none")
+	fs.BoolVar(&opts.quiet, "quiet", false, "Print nothing but errors, neither progress nor the summary")
+	fs.StringVar(&opts.metricsAddr, "metrics-addr", "", "Address to serve Prometheus metrics on at /metrics, e.g. :9100")
+	fs.StringVar(&opts.manifestDir, "manifest-dir", manifest.DefaultDir, "Directory to record keys of inserted rows in for syndi cleanup, empty disables it")
+	fs.StringVar(&opts.rate, "rate", "", "Keep inserting rows at this rate, e.g. 500/s, 3000/m or 100000/h, instead of TotalRecords")
+	fs.DurationVar(&opts.load.Duration, "duration", 0, "How long to keep inserting rows with -rate, 0 means until interrupted")
+	fs.StringVar(&opts.load.Profile, "load-profile", importer.ProfileFlat, "How the -rate changes over time: flat, diurnal or spikes")
+	fs.Float64Var(&opts.load.Jitter, "jitter", 0, "Random variation of the -rate, e.g. 0.2 for ±20%")
+	fs.BoolVar(&opts.load.ExistingRows, "workload-existing", false, "Let workloads update and delete rows that were in the tables before the run")
+}
+
+// TODO: have concurrent clients for faster import...
+func importCmd(argv []string) {
+	args := config.RunArgs{}
+	opts := importOptions{}
+	fs := newFlagSet("syndi", "syndi [flags] <table.yaml>...")
+	runArgsFlags(fs, &args)
+	importOptionsFlags(fs, &opts)
+	fs.StringVar(&args.ConfigFile, "config", "", "Run config file with a Connection section")
+	_ = fs.Parse(argv)
+	args.Tables = fs.Args()
+	importData(args, opts)
+}
+
+func runCmd(argv []string) {
+	args := config.RunArgs{}
+	opts := importOptions{}
+	fs := newFlagSet("syndi run", "syndi run [flags] <syndi.yaml>")
+	runArgsFlags(fs, &args)
+	importOptionsFlags(fs, &opts)
+	_ = fs.Parse(argv)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		log.Fatal("exactly one run config file is expected")
+	}
+	args.ConfigFile = fs.Arg(0)
+	importData(args, opts)
+}
+
+func importData(args config.RunArgs, opts importOptions) {
+	// load configuration
+	run, err := config.LoadConfig(args)
+	if err != nil {
+		log.Panicf("error loading config: %#v:", err)
+	}
+	if run.Seed != 0 {
+		generators.SetSeed(run.Seed)
+	}
+
+	var loads []importer.LoadOptions
+	if opts.rate != "" {
+		loads, err = tableLoads(run.Tables, opts)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	if opts.quiet {
+		opts.progress = progress.None
+		log.SetOutput(ioutil.Discard)
+	}
+	tracker, err := progress.NewTracker(os.Stderr, opts.progress, progressInterval)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	if opts.metricsAddr != "" {
+		serveMetrics(opts.metricsAddr)
+	}
+
+	// connect to db
+	db := openDB(run.DSN)
+	defer db.Close()
+
+	var m *manifest.Manifest
+	if opts.manifestDir != "" {
+		now := time.Now()
+		m = manifest.New(manifest.NewRunID(now), now)
+		defer flushManifest(m, opts.manifestDir)()
+	}
+
+	importers := make([]*importer.Importer, 0, len(run.Tables))
+	for _, tableDef := range run.Tables {
+		if opts.createTables && tableDef.Mask == nil {
+			err = createTable(db, tableDef)
+			if err != nil {
+				log.Panic(err)
+			}
+		}
+		im, err := importer.NewImporter(db, tableDef)
+		if err != nil {
+			log.Panic(err)
+		}
+		total := tableDef.TotalRecords
+		if tableDef.Mask != nil {
+			total = 0 // Unknown until the source is read.
+		}
+		if loads != nil {
+			total = int(loads[len(importers)].Rate * opts.load.Duration.Seconds())
+		}
+		im.TrackProgress(tracker.AddTable(tableDef.TableName, total))
+		if m != nil {
+			err = im.RecordKeys(m)
+			if err != nil {
+				log.Printf("not recording rows inserted into %s for cleanup: %v", tableDef.TableName, err)
+			}
+		}
+		if !opts.skipPreflight {
+			err = im.Preflight()
+			if err != nil {
+				log.Panic(err)
+			}
+		}
+		importers = append(importers, im)
+	}
+
+	// import things
+	stop, release := stopOnSignal()
+	defer release()
+	tracker.Start()
+	if loads != nil {
+		loadTables(db, run, importers, loads, stop)
+	} else {
+		importTables(db, run, importers, stop)
+	}
+	tracker.Stop()
+	if !opts.quiet {
+		err = tracker.WriteSummary(os.Stdout)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+}
+
+// createTable creates the table unless it exists already.
+func createTable(db *sql.DB, tdef *config.TableDef) error {
+	ddl, err := schema.MySQL.CreateTable(tdef)
+	if err != nil {
+		return fmt.Errorf("%s: %w", tdef.TableName, err)
+	}
+	log.Printf("creating table %s if missing", tdef.TableName)
+	_, err = db.Exec(ddl)
+	return err
+}
+
+// flushManifest appends the recorded keys to the manifest every manifestInterval, so that rows of runs that are killed
+// can be cleaned up as well. The returned func stops flushing and flushes the rest, it's deferred so that rows of
+// failed and interrupted runs are recorded too.
+func flushManifest(m *manifest.Manifest, dir string) (stop func()) {
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(manifestInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := m.Flush(dir); err != nil {
+					log.Printf("unable to save the manifest of run %s: %v", m.RunID, err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+		if len(m.Tables) == 0 {
+			return
+		}
+		if err := m.Flush(dir); err != nil {
+			log.Printf("unable to save the manifest of run %s: %v", m.RunID, err)
+			return
+		}
+		log.Printf("rows inserted by run %s can be deleted with: syndi cleanup %s", m.RunID, m.RunID)
+	}
+}
+
+// stopOnSignal returns a channel closed on SIGINT or SIGTERM, so that syndi can stop after the current batch and still
+// save the manifest. A second signal kills it right away. Release stops listening.
+func stopOnSignal() (stop <-chan struct{}, release func()) {
+	stopped, released := make(chan struct{}), make(chan struct{})
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sig:
+			signal.Stop(sig)
+			log.Println("stopping after the current batch, interrupt again to quit right away")
+			close(stopped)
+		case <-released:
+		}
+	}()
+	return stopped, func() {
+		signal.Stop(sig)
+		close(released)
+	}
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// session takes a connection out of the pool, so that settings of the session made by hooks or by disabling foreign
+// key checks apply to all statements run on it.
+func session(db *sql.DB) *sql.Conn {
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		log.Panic(err)
+	}
+	return conn
+}
+
+// importTables imports the tables one by one, each with its hooks, on a single connection. Closing stop skips the
+// remaining tables and hooks.
+func importTables(db *sql.DB, run *config.Run, importers []*importer.Importer, stop <-chan struct{}) {
+	conn := session(db)
+	defer conn.Close()
+	err := importer.RunHooks(conn, run.Hooks.Before)
+	if err != nil {
+		log.Panic(err)
+	}
+	for i, im := range importers {
+		tableDef := run.Tables[i]
+		im.UseConn(conn)
+		err = importer.RunHooks(conn, tableDef.Hooks.Before)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		err = im.DisableFK()
+		if err != nil {
+			log.Panic(err)
+		}
+
+		err = im.ImportUntil(stop)
+		if err != nil {
+			log.Panic(err)
+		}
+		err = im.EnableFK()
+		if err != nil {
+			log.Panic(err)
+		}
+		if isClosed(stop) {
+			log.Println("import interrupted, skipping the remaining tables and hooks")
+			return
+		}
+
+		err = importer.RunHooks(conn, tableDef.Hooks.After)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+	err = importer.RunHooks(conn, run.Hooks.After)
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+// tableLoads splits the -rate between the tables in the ratio of their TotalRecords.
+func tableLoads(tables []*config.TableDef, opts importOptions) ([]importer.LoadOptions, error) {
+	rate, err := config.ParseRate(opts.rate)
+	if err != nil {
+		return nil, err
+	}
+	total := 0
+	for _, tdef := range tables {
+		if tdef.Mask != nil {
+			return nil, fmt.Errorf("%s: masking can't be combined with -rate", tdef.TableName)
+		}
+		for _, col := range tdef.Columns {
+			if col.Sorted {
+				// Sorted values are spread over TotalRecords, which the load mode doesn't stop at.
+				return nil, fmt.Errorf("%s: Sorted column %s can't be combined with -rate", tdef.TableName, col.Name)
+			}
+		}
+		total += tdef.TotalRecords
+	}
+	loads := make([]importer.LoadOptions, 0, len(tables))
+	for _, tdef := range tables {
+		load := opts.load
+		load.Rate = rate * float64(tdef.TotalRecords) / float64(total)
+		if err = load.Check(); err != nil {
+			return nil, fmt.Errorf("%s: %w", tdef.TableName, err)
+		}
+		loads = append(loads, load)
+	}
+	return loads, nil
+}
+
+// loadTables keeps inserting rows into all tables concurrently until the duration passes or stop is closed. Every
+// table is written on a connection of its own, where its hooks run before the first and after the last insert. The
+// hooks of the run are run on another connection, so their session settings don't apply to the tables.
+func loadTables(db *sql.DB, run *config.Run, importers []*importer.Importer, loads []importer.LoadOptions,
+	stop <-chan struct{}) {
+	conn := session(db)
+	defer conn.Close()
+	err := importer.RunHooks(conn, run.Hooks.Before)
+	if err != nil {
+		log.Panic(err)
+	}
+	conns := make([]*sql.Conn, len(importers))
+	for i, im := range importers {
+		conns[i] = session(db)
+		defer conns[i].Close()
+		im.UseConn(conns[i])
+		err = importer.RunHooks(conns[i], run.Tables[i].Hooks.Before)
+		if err != nil {
+			log.Panic(err)
+		}
+		err = im.DisableFK()
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	errs := make(chan error, len(importers))
+	for i, im := range importers {
+		go func(im *importer.Importer, load importer.LoadOptions) {
+			errs <- im.Load(load, stop)
+		}(im, loads[i])
+	}
+	for range importers {
+		if err := <-errs; err != nil {
+			log.Panic(err)
+		}
+	}
+
+	for i, im := range importers {
+		err = im.EnableFK()
+		if err != nil {
+			log.Panic(err)
+		}
+		err = importer.RunHooks(conns[i], run.Tables[i].Hooks.After)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+	err = importer.RunHooks(conn, run.Hooks.After)
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+// serveMetrics starts serving the metrics in the background, failing right away if the address can't be used.
+func serveMetrics(addr string) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Panic(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Default.Handler())
+	log.Printf("serving metrics on http://%s/metrics", l.Addr())
+	go func() {
+		log.Println(http.Serve(l, mux))
+	}()
+}
+
+func openDB(dsn string) *sql.DB {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Panic(err)
+	}
+	err = db.Ping()
+	if err != nil {
+		log.Panic(err)
+	}
+	return db
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/schema"
+	"gopkg.in/yaml.v2"
+)
+
+func initCmd(argv []string) {
+	args := config.RunArgs{}
+	var table, output string
+	var totalRecords, batchSize int
+	fs := newFlagSet("syndi init", "syndi init [flags] -table <name>")
+	connectionFlags(fs, &args.Connection)
+	fs.StringVar(&args.ConfigFile, "config", "", "Run config file with a Connection section")
+	fs.StringVar(&args.OptionFile, "defaults-file", "", "MySQL option file to read [client] and [syndi] groups from (default: ~/.my.cnf)")
+	fs.StringVar(&table, "table", "", "Table to generate the config for")
+	fs.StringVar(&output, "o", "", "File to write the config to (default: stdout)")
+	fs.IntVar(&totalRecords, "records", 1000, "TotalRecords of the generated config")
+	fs.IntVar(&batchSize, "batch", 500, "BatchSize of the generated config")
+	_ = fs.Parse(argv)
+	if table == "" {
+		fs.Usage()
+		log.Fatal("-table is required")
+	}
+
+	dsn, err := args.GetDSN()
+	if err != nil {
+		log.Panicf("error loading config: %#v:", err)
+	}
+	db := openDB(dsn)
+	defer db.Close()
+
+	t, err := schema.LoadTable(db, table)
+	if err != nil {
+		log.Panic(err)
+	}
+	err = schema.LoadRefs(db, t)
+	if err != nil {
+		log.Panic(err)
+	}
+	tdef := schema.SuggestTableDef(t, totalRecords, batchSize)
+	writeTableDef(tdef, fmt.Sprintf("Generated by syndi init from the schema of %s, review before use.", table), output)
+}
+
+// writeTableDef writes the table definition with a header comment to the output file or stdout.
+func writeTableDef(tdef *config.TableDef, header, output string) {
+	data, err := yaml.Marshal(tdef)
+	if err != nil {
+		log.Panic(err)
+	}
+	data = append([]byte("# "+header+"\n"), data...)
+
+	if output == "" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = ioutil.WriteFile(output, data, 0644)
+	}
+	if err != nil {
+		log.Panic(err)
+	}
+}
@@ -1,59 +1,75 @@
 package main
 
 import (
-	"database/sql"
 	"flag"
-	"log"
+	"fmt"
+	"os"
 
-	"github.com/bitstonks/syndi/internal/config"
-	"github.com/bitstonks/syndi/internal/importer"
-
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/bitstonks/syndi/pkg/syndi"
 )
 
-// TODO 1: generate in one goroutine and import in another...
-// TODO 2: have concurrent clients for faster import...
-func main() {
-	// save command-line arguments
-	args := config.RunArgs{}
-	flag.StringVar(&args.Database, "db", "bitstamp_dev", "Database name to use")
-	flag.StringVar(&args.Host, "host", "localhost", "Database host to connect to")
-	flag.StringVar(&args.Password, "p", "root", "Database user's password")
-	flag.StringVar(&args.Port, "P", "28000", "Database port number")
-	flag.BoolVar(&args.Safe, "safe", false, "Whether foreign key checks are mandated")
-	flag.StringVar(&args.User, "u", "root", "Database user")
-	flag.Parse()
-	args.Tables = flag.Args()
+const usage = `Usage:
+  syndi [flags] <table.yaml>...        import data for the given table definitions
+  syndi run [flags] <syndi.yaml>       import the dataset described by a run config file
+  syndi init [flags] -table <name>     generate a starter table definition from an existing table
+  syndi profile [flags] -table <name>  generate a table definition mimicking the data of an existing table
+  syndi ddl [flags] <table.yaml>...    print CREATE TABLE statements for the given table definitions
+  syndi preview [flags] <table.yaml>   print sample rows and column stats without a database
+  syndi cleanup [flags] [run-id]       delete the rows inserted by a run, or list the runs
+  syndi version                        print the version
 
-	// load configuration
-	dbDSN, tableDefinitions, err := config.LoadConfig(args)
-	if err != nil {
-		log.Panicf("error loading config: %#v:", err)
-	}
+Run "syndi <command> -h" for flags of a command.
+`
 
-	// connect to db
-	db, err := sql.Open("mysql", dbDSN)
-	if err != nil {
-		log.Panic(err)
-	}
-	defer db.Close()
-	err = db.Ping()
-	if err != nil {
-		log.Panic(err)
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "run":
+			runCmd(os.Args[2:])
+			return
+		case "init":
+			initCmd(os.Args[2:])
+			return
+		case "profile":
+			profileCmd(os.Args[2:])
+			return
+		case "ddl":
+			ddlCmd(os.Args[2:])
+			return
+		case "preview":
+			previewCmd(os.Args[2:])
+			return
+		case "cleanup":
+			cleanupCmd(os.Args[2:])
+			return
+		case "version":
+			fmt.Println(syndi.Version)
+			return
+		case "help":
+			fmt.Fprint(os.Stderr, usage)
+			return
+		}
 	}
+	importCmd(os.Args[1:])
+}
 
-	// import things
-	for _, tableDef := range tableDefinitions {
-		im := importer.NewImporter(db, tableDef)
-		err = im.DisableFK()
-		if err != nil {
-			log.Panic(err)
-		}
+// newFlagSet creates a flag set for a subcommand with a usage message that lists its flags.
+func newFlagSet(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s\n\nFlags:\n", synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
 
-		err = im.Import()
-		if err != nil {
-			log.Panic(err)
-		}
-		im.EnableFK() // TODO: this can now fail to run, not sure whether it is a problem since it's connection-bound (?)
+// parseInterspersed parses flags that can also come after positional arguments and returns the positional ones.
+func parseInterspersed(fs *flag.FlagSet, argv []string) []string {
+	var args []string
+	_ = fs.Parse(argv)
+	for fs.NArg() > 0 {
+		args = append(args, fs.Arg(0))
+		_ = fs.Parse(fs.Args()[1:])
 	}
+	return args
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/generators"
+	"github.com/bitstonks/syndi/internal/preview"
+)
+
+func previewCmd(argv []string) {
+	var vars config.Vars
+	var n, sample int
+	var seed int64
+	var asJSON bool
+	fs := newFlagSet("syndi preview", "syndi preview [flags] <table.yaml>")
+	fs.IntVar(&n, "n", 10, "Number of rows to print")
+	fs.IntVar(&sample, "stats", 1000, "Number of rows to compute column stats from, 0 skips stats")
+	fs.BoolVar(&asJSON, "json", false, "Print rows as JSON objects, one per line, without stats")
+	fs.Int64Var(&seed, "seed", 0, "Seed for reproducible data, 0 means random")
+	fs.Var(&vars, "var", "Variable used in table definitions as ${key}, given as key=value (can be repeated)")
+	files := parseInterspersed(fs, argv)
+	if len(files) != 1 {
+		fs.Usage()
+		log.Fatal("exactly one table definition is expected")
+	}
+	if seed != 0 {
+		generators.SetSeed(seed)
+	}
+
+	tdef, err := config.LoadTableDef(files[0], vars)
+	if err != nil {
+		log.Panic(err)
+	}
+	names := tdef.Columns.Names()
+	rows, err := preview.Generate(tdef, n)
+	if err != nil {
+		log.Panic(err)
+	}
+	if asJSON {
+		err = preview.WriteJSON(os.Stdout, names, rows)
+		if err != nil {
+			log.Panic(err)
+		}
+		return
+	}
+	err = preview.WriteTable(os.Stdout, names, rows)
+	if err != nil {
+		log.Panic(err)
+	}
+	if sample <= 0 {
+		return
+	}
+
+	rows, err = preview.Generate(tdef, sample)
+	if err != nil {
+		log.Panic(err)
+	}
+	fmt.Printf("\nStats over %d rows:\n", sample)
+	err = preview.WriteStats(os.Stdout, preview.ColumnStats(names, rows))
+	if err != nil {
+		log.Panic(err)
+	}
+}
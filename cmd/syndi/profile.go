@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/schema"
+)
+
+func profileCmd(argv []string) {
+	args := config.RunArgs{}
+	opts := schema.DefaultProfileOptions
+	var table, output string
+	var totalRecords, batchSize int
+	fs := newFlagSet("syndi profile", "syndi profile [flags] -table <name>")
+	connectionFlags(fs, &args.Connection)
+	fs.StringVar(&args.ConfigFile, "config", "", "Run config file with a Connection section")
+	fs.StringVar(&args.OptionFile, "defaults-file", "", "MySQL option file to read [client] and [syndi] groups from (default: ~/.my.cnf)")
+	fs.StringVar(&table, "table", "", "Table to profile")
+	fs.StringVar(&output, "o", "", "File to write the config to (default: stdout)")
+	fs.IntVar(&totalRecords, "records", 1000, "TotalRecords of the generated config")
+	fs.IntVar(&batchSize, "batch", 500, "BatchSize of the generated config")
+	fs.IntVar(&opts.Sample, "sample", opts.Sample, "Maximum number of rows to scan, 0 scans the whole table")
+	fs.IntVar(&opts.TopK, "top-k", opts.TopK, "Columns with at most this many distinct values are generated from their values")
+	fs.Float64Var(&opts.MinFreq, "min-freq", opts.MinFreq, "Values less frequent than this are never written to the config")
+	fs.IntVar(&opts.MinCount, "min-count", opts.MinCount, "Values occurring fewer times than this are never written to the config")
+	fs.IntVar(&opts.Buckets, "buckets", opts.Buckets, "Number of histogram buckets")
+	_ = fs.Parse(argv)
+	if table == "" {
+		fs.Usage()
+		log.Fatal("-table is required")
+	}
+
+	dsn, err := args.GetDSN()
+	if err != nil {
+		log.Panicf("error loading config: %#v:", err)
+	}
+	db := openDB(dsn)
+	defer db.Close()
+
+	t, err := schema.LoadTable(db, table)
+	if err != nil {
+		log.Panic(err)
+	}
+	err = schema.LoadRefs(db, t)
+	if err != nil {
+		log.Panic(err)
+	}
+	tdef, err := schema.Profile(db, t, totalRecords, batchSize, opts)
+	if err != nil {
+		log.Panic(err)
+	}
+	writeTableDef(tdef, fmt.Sprintf("Generated by syndi profile from the data of %s, review before use.", table), output)
+}
@@ -6,87 +6,367 @@ import (
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
 	"log"
+	"math"
+	"os"
+	"strconv"
 )
 
 // RunArgs is a container for command-line flags passed in.
 type RunArgs struct {
-	Database string `validate:"required"`
-	Host     string `validate:"required"`
-	Password string `validate:"required"`
-	Port     string `validate:"required,number,gt=0"`
-	Safe     bool
-	Tables   []string `validate:"required,gt=0"`
-	User     string   `validate:"required"`
-}
-
-func (a RunArgs) GetDSN() string {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&interpolateParams=true",
-		a.User,
-		a.Password,
-		a.Host,
-		a.Port,
-		a.Database,
-	)
-	return dsn
+	Connection Connection   `validate:"-"` // Flags only, see ResolveConnection for other sources.
+	ConfigFile string       // Run config file with settings shared by all tables.
+	OptionFile string       // MySQL option file, DefaultOptionFile is used if it exists and this is empty.
+	Safe       OptionalBool // Unset unless given as a flag, the run config file decides then.
+	Scale      float64      `validate:"omitempty,gt=0"` // Zero means no scaling.
+	Seed       int64        // Zero means a random seed.
+	Tables     []string     `validate:"required,gt=0"`
+	Vars       Vars
+}
+
+// OptionalBool is a bool that also tells whether it was set at all. It implements flag.Value, so that a flag such as
+// `-safe=false` can override the value from a run config file.
+type OptionalBool struct {
+	Bool  bool
+	Valid bool // Whether Bool was set.
+}
+
+func (b OptionalBool) String() string {
+	if !b.Valid {
+		return ""
+	}
+	return strconv.FormatBool(b.Bool)
+}
+
+func (b *OptionalBool) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	b.Bool, b.Valid = v, true
+	return nil
+}
+
+// IsBoolFlag lets the flag be given without a value, like the flags of the flag package.
+func (b *OptionalBool) IsBoolFlag() bool {
+	return true
+}
+
+// ResolveConnection merges connection settings from all the sources in order of precedence: command-line flags,
+// environment, run config file, MySQL option file and finally DefaultConnection.
+func (a RunArgs) ResolveConnection() (Connection, error) {
+	conn := a.Connection
+	conn.Merge(ConnectionFromEnv())
+	if a.ConfigFile != "" {
+		rc, err := LoadRunConfig(a.ConfigFile, a.Vars)
+		if err != nil {
+			return conn, err
+		}
+		conn.Merge(rc.Connection)
+	}
+	optionFile := a.OptionFile
+	if optionFile == "" {
+		if _, err := os.Stat(expandHome(DefaultOptionFile)); err == nil {
+			optionFile = DefaultOptionFile
+		}
+	}
+	if optionFile != "" {
+		opts, err := ReadOptionFile(optionFile)
+		if err != nil {
+			return conn, err
+		}
+		conn.Merge(opts)
+	}
+	conn.Merge(DefaultConnection)
+	return conn, nil
+}
+
+func (a RunArgs) GetDSN() (string, error) {
+	conn, err := a.ResolveConnection()
+	if err != nil {
+		return "", err
+	}
+	err = validator.New().Struct(conn)
+	if err != nil {
+		reportValidationErrors(err)
+		return "", err
+	}
+	return conn.GetDSN()
 }
 
 // ColumnDef defines the type of data we want inserted into a single column of a particular database table.
 type ColumnDef struct {
-	Type     string  `yaml:"Type" validate:"required"`
-	Nullable float64 `yaml:"Nullable" validate:"optional"`
-	First    string  `yaml:"First" validate:"optional"`
-	MinVal   string  `yaml:"MinVal" validate:"optional"`
-	MaxVal   string  `yaml:"MaxVal" validate:"optional"`
-	OneOf    string  `yaml:"OneOf" validate:"optional"`
-	Length   int     `yaml:"Length" validate:"optional"`
-	Format   string  `yaml:"Format" validate:"optional"`
+	Type     string  `yaml:"Type,omitempty" validate:"required"`
+	Nullable float64 `yaml:"Nullable,omitempty" validate:"optional"`
+	First    string  `yaml:"First,omitempty" validate:"optional"`
+	MinVal   string  `yaml:"MinVal,omitempty" validate:"optional"`
+	MaxVal   string  `yaml:"MaxVal,omitempty" validate:"optional"`
+	OneOf    string  `yaml:"OneOf,omitempty" validate:"optional"`
+	Length   int     `yaml:"Length,omitempty" validate:"optional"`
+	Lengths  string  `yaml:"Lengths,omitempty" validate:"optional"` // Weighted lengths, overrides Length.
+	Format   string  `yaml:"Format,omitempty" validate:"optional"`
+	SQLType  string  `yaml:"SQLType,omitempty" validate:"optional"` // Column type for CREATE TABLE, inferred if empty.
+	Ref      string  `yaml:"Ref,omitempty" validate:"optional"`     // Referenced table.column, see resolveRefs.
+
+	// Datetimes
+	Sorted    bool   `yaml:"Sorted,omitempty" validate:"optional"`    // Non-decreasing uniform values spread over the range.
+	Rate      string `yaml:"Rate,omitempty" validate:"optional"`      // Poisson arrival rate of incremental values, e.g. 10/s.
+	Rows      int    `yaml:"-"`                                       // Number of values generated, set by the importer.
+	Layout    string `yaml:"Layout,omitempty" validate:"optional"`    // Go layout of First, MinVal and MaxVal.
+	TimeZone  string `yaml:"TimeZone,omitempty" validate:"optional"`  // Location of parsed and generated values, UTC if empty.
+	Precision int    `yaml:"Precision,omitempty" validate:"optional"` // Digits of fractional seconds, 0 to 6.
+	Epoch     string `yaml:"Epoch,omitempty" validate:"optional"`     // Unix epoch integers in s, ms or us instead of datetimes.
+	Hours     string `yaml:"Hours,omitempty" validate:"optional"`     // Weights of hours of day, e.g. "0..24:1;9..17:10".
+	Weekdays  string `yaml:"Weekdays,omitempty" validate:"optional"`  // Weights of days of week, e.g. "mon..fri:5;sat..sun:1".
+	Months    string `yaml:"Months,omitempty" validate:"optional"`    // Weights of months, e.g. "jan..dec:1;nov..dec:3".
+
+	// Random walks
+	Drift      float64  `yaml:"Drift,omitempty" validate:"optional"`      // Expected relative change per row.
+	Volatility *float64 `yaml:"Volatility,omitempty" validate:"optional"` // Standard deviation of the relative change per row, 1% if nil.
+	Reversion  float64  `yaml:"Reversion,omitempty" validate:"optional"`  // Pull back towards First per row, 0 to 1.
+}
+
+// Column is a ColumnDef together with the name of the column it describes.
+type Column struct {
+	Name string
+	ColumnDef
+}
+
+// Columns is an ordered list of column definitions. It is written in YAML as a mapping from column names to their
+// definitions, but unlike a map it keeps the order in which the columns were written. This order is then used for
+// generating the values and for the columns of the INSERT statements.
+type Columns []Column
+
+func (c *Columns) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var order yaml.MapSlice
+	if err := unmarshal(&order); err != nil {
+		return err
+	}
+	defs := map[string]ColumnDef{}
+	if err := unmarshal(&defs); err != nil {
+		return err
+	}
+	cols := make(Columns, 0, len(order))
+	seen := map[string]bool{}
+	for _, item := range order {
+		name := fmt.Sprint(item.Key)
+		if name == "" {
+			return fmt.Errorf("column name should not be empty")
+		}
+		if seen[name] {
+			return fmt.Errorf("column %q is defined more than once", name)
+		}
+		seen[name] = true
+		cols = append(cols, Column{Name: name, ColumnDef: defs[name]})
+	}
+	*c = cols
+	return nil
+}
+
+func (c Columns) MarshalYAML() (interface{}, error) {
+	res := make(yaml.MapSlice, 0, len(c))
+	for _, col := range c {
+		res = append(res, yaml.MapItem{Key: col.Name, Value: col.ColumnDef})
+	}
+	return res, nil
+}
+
+// Names returns column names in order.
+func (c Columns) Names() []string {
+	names := make([]string, 0, len(c))
+	for _, col := range c {
+		names = append(names, col.Name)
+	}
+	return names
 }
 
 // TableDef describes one particular database table. Its data is (mostly) loaded from a YAML file.
 type TableDef struct {
-	TableName    string               `yaml:"TableName" validate:"required"`
-	TotalRecords int                  `yaml:"TotalRecords" validate:"required,gt=0"`
-	BatchSize    int                  `yaml:"BatchSize" validate:"required,gt=0"`
-	SafeImport   bool                 // TODO: should this be global?
-	Columns      map[string]ColumnDef `yaml:"Columns" validate:"required,dive,keys,required,endkeys"`
+	TableName    string       `yaml:"TableName" validate:"required"`
+	TotalRecords int          `yaml:"TotalRecords,omitempty" validate:"required_without=Mask,gte=0"` // Ignored when masking.
+	BatchSize    int          `yaml:"BatchSize" validate:"required,gt=0"`
+	BatchBytes   int          `yaml:"BatchBytes,omitempty" validate:"gte=0"`         // Size limit of one INSERT statement.
+	ScaleWith    *float64     `yaml:"ScaleWith,omitempty" validate:"omitempty,gt=0"` // Overrides the global scale factor.
+	SafeImport   bool         `yaml:"safeimport,omitempty"`                          // TODO: should this be global?
+	Mask         *MaskDef     `yaml:"Mask,omitempty"`
+	OnConflict   ConflictDef  `yaml:"OnConflict,omitempty"`
+	Workload     *WorkloadDef `yaml:"Workload,omitempty"` // Used in the load mode only.
+	Hooks        Hooks        `yaml:"Hooks,omitempty"`
+	Columns      Columns      `yaml:"Columns" validate:"required"`
+}
+
+// MaskDef turns the import into masking of existing data: rows are read from the Source table in batches ordered by
+// the Key and written to TableName, with the configured columns replaced by generated values and the other columns
+// copied verbatim.
+type MaskDef struct {
+	Source string `yaml:"Source,omitempty"`           // Defaults to TableName, which masks the table in place.
+	Key    string `yaml:"Key,omitempty"`              // Unique NOT NULL column, the single-column primary key if not set.
+	Secret string `yaml:"Secret" validate:"required"` // Key of the hash deriving fake values from the real ones.
 }
 
-func LoadConfig(args RunArgs) (string, []*TableDef, error) {
-	dsn := args.GetDSN()
-	tables := make([]*TableDef, 0)
+// Actions on inserted rows conflicting with existing ones on a unique key.
+const (
+	ConflictError   = "error"   // Fail the import, which is the default.
+	ConflictIgnore  = "ignore"  // Skip the inserted row.
+	ConflictUpdate  = "update"  // Update the existing row with the Update columns of the inserted one.
+	ConflictReplace = "replace" // Replace the existing row with the inserted one.
+)
+
+// ConflictDef tells what to do with inserted rows conflicting with existing ones. It can be written in YAML just as
+// the action, e.g. `OnConflict: ignore`.
+type ConflictDef struct {
+	Action string   `yaml:"Action,omitempty" validate:"omitempty,oneof=error ignore update replace"`
+	Key    []string `yaml:"Key,omitempty"`    // Columns of the unique key, PostgreSQL needs them to update or replace.
+	Update []string `yaml:"Update,omitempty"` // Columns updated by the update action, all but the Key if empty.
+}
+
+func (c *ConflictDef) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var action string
+	if err := unmarshal(&action); err == nil {
+		*c = ConflictDef{Action: action}
+		return nil
+	}
+	type plain ConflictDef
+	return unmarshal((*plain)(c))
+}
+
+// WorkloadDef mixes updates and deletes of previously inserted rows into the inserts of the load mode.
+type WorkloadDef struct {
+	Key        string   `yaml:"Key" validate:"required"`        // Column identifying the rows, one of Columns.
+	Operations string   `yaml:"Operations" validate:"required"` // Weighted like OneOf, e.g. "insert:60;update:30;delete:10".
+	Update     []string `yaml:"Update,omitempty"`               // Columns set to newly generated values by updates.
+}
+
+// Index returns the position of the named column, or -1 if there's no such column.
+func (c Columns) Index(name string) int {
+	for i, col := range c {
+		if col.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// SourceTable returns the table the rows are masked from.
+func (t *TableDef) SourceTable() string {
+	if t.Mask == nil || t.Mask.Source == "" {
+		return t.TableName
+	}
+	return t.Mask.Source
+}
+
+// LoadTableDef reads a table definition from a YAML file, interpolating variables first. It is not validated yet,
+// so that defaults and overrides can still be applied.
+func LoadTableDef(path string, vars Vars) (*TableDef, error) {
+	yamlFile, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	yamlFile, err = Interpolate(yamlFile, vars)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	tdef := &TableDef{}
+	err = yaml.Unmarshal(yamlFile, tdef)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return tdef, nil
+}
+
+// Scale multiplies TotalRecords by the given factor unless the table sets its own ScaleWith factor, which is used
+// instead (use `ScaleWith: 1` for fixed-size tables). The result is rounded, but never scaled down to zero records.
+func (t *TableDef) Scale(factor float64) {
+	if t.ScaleWith != nil {
+		factor = *t.ScaleWith
+	}
+	if factor <= 0 || factor == 1 || t.TotalRecords <= 0 {
+		return
+	}
+	t.TotalRecords = int(math.Max(1, math.Round(float64(t.TotalRecords)*factor)))
+}
+
+// LoadConfig loads the run config file (if any) and all the table definitions into a Run. Command-line arguments take
+// precedence over the run config file. When no tables are passed in, the ones listed in the run config file are used.
+func LoadConfig(args RunArgs) (*Run, error) {
+	run := &Run{}
 	validate := validator.New()
 
-	err := validate.Struct(args)
+	rc := &RunConfig{}
+	if args.ConfigFile != "" {
+		var err error
+		rc, err = LoadRunConfig(args.ConfigFile, args.Vars)
+		if err != nil {
+			return run, err
+		}
+	}
+	err := validate.Struct(rc)
 	if err != nil {
 		reportValidationErrors(err)
-		return dsn, tables, err
+		return run, err
+	}
+	vars := Vars{}
+	for k, v := range rc.Vars {
+		vars[k] = v
+	}
+	for k, v := range args.Vars {
+		vars[k] = v
+	}
+	args.Vars = vars
+	if args.Scale == 0 {
+		args.Scale = rc.Scale
+	}
+	if args.Seed == 0 {
+		args.Seed = rc.Seed
+	}
+	if !args.Safe.Valid {
+		args.Safe = OptionalBool{Bool: rc.Safe, Valid: true}
+	}
+	refs := rc.Tables
+	if len(args.Tables) > 0 {
+		refs = make([]TableRef, 0, len(args.Tables))
+		for _, tableFile := range args.Tables {
+			refs = append(refs, TableRef{File: tableFile})
+		}
+	} else {
+		for _, ref := range refs {
+			args.Tables = append(args.Tables, ref.File)
+		}
 	}
 
+	err = validate.Struct(args)
+	if err != nil {
+		reportValidationErrors(err)
+		return run, err
+	}
+	run.DSN, err = args.GetDSN()
+	if err != nil {
+		return run, err
+	}
+	run.Seed = args.Seed
+	run.Hooks = rc.Hooks
+
 	// TODO: allow for entire folders to be passed in and implement searching for YAML files in them
-	for _, tableFile := range args.Tables {
-		yamlFile, err := ioutil.ReadFile(tableFile)
-		if err != nil {
-			return dsn, tables, err
-		}
-		tdef := &TableDef{}
-		err = yaml.Unmarshal(yamlFile, &tdef)
+	for _, ref := range refs {
+		tdef, err := LoadTableDef(ref.File, args.Vars)
 		if err != nil {
-			return dsn, tables, err
+			return run, err
 		}
-		if tdef.BatchSize > tdef.TotalRecords {
-			log.Printf("%s: BatchSize larger than TotalRecords, setting the former to equal the latter.\n", tableFile)
+		ref.apply(tdef, rc.Defaults)
+		tdef.Scale(args.Scale)
+		if tdef.Mask == nil && tdef.BatchSize > tdef.TotalRecords {
+			log.Printf("%s: BatchSize larger than TotalRecords, setting the former to equal the latter.\n", ref.File)
 			tdef.BatchSize = tdef.TotalRecords
 		}
 		err = validate.Struct(tdef)
 		if err != nil {
 			reportValidationErrors(err)
-			return dsn, tables, err
+			return run, err
 		}
-		tdef.SafeImport = args.Safe
-		tables = append(tables, tdef)
+		tdef.SafeImport = args.Safe.Bool
+		run.Tables = append(run.Tables, tdef)
 	}
 
-	return dsn, tables, err
+	return run, resolveRefs(run.Tables)
 }
 
 func reportValidationErrors(err error) {
@@ -7,18 +7,31 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
 )
 
+// isolateConnection makes sure connection settings of the machine running tests are not picked up.
+func isolateConnection(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, env := range []string{"SYNDI_DSN", "MYSQL_HOST", "MYSQL_TCP_PORT", "MYSQL_UNIX_PORT", "MYSQL_PWD"} {
+		t.Setenv(env, "")
+	}
+}
+
 func TestRunArgs(t *testing.T) {
+	isolateConnection(t)
+	conn := Connection{
+		Database: "example",
+		Host:     "localhost",
+		Password: "root",
+		Port:     "3306",
+		User:     "root",
+	}
+
 	t.Run("test initialization happy path", func(t *testing.T) {
 		args := RunArgs{
-			Database: "example",
-			Host:     "localhost",
-			Password: "root",
-			Port:     "3306",
-			Safe:     false,
-			Tables:   []string{"users.yaml", "accounts.yaml"},
-			User:     "root",
+			Connection: conn,
+			Tables:     []string{"users.yaml", "accounts.yaml"},
 		}
 		validate := validator.New()
 		err := validate.Struct(args)
@@ -27,18 +40,18 @@ func TestRunArgs(t *testing.T) {
 
 	t.Run("test initialization sad path", func(t *testing.T) {
 		args := RunArgs{
-			Database: "example",
-			Host:     "localhost",
-			Password: "root",
-			Port:     "invalid port number",
-			Safe:     false,
-			User:     "root",
+			Connection: conn,
+			Scale:      -1,
 		}
+		args.Connection.Port = "invalid port number"
 		validate := validator.New()
 		err := validate.Struct(args)
-		assert.EqualError(t, err, "Key: 'RunArgs.Port' Error:Field validation for 'Port' failed on the 'number' tag\nKey: 'RunArgs.Tables' Error:Field validation for 'Tables' failed on the 'required' tag")
+		assert.EqualError(t, err, "Key: 'RunArgs.Scale' Error:Field validation for 'Scale' failed on the 'gt' tag\nKey: 'RunArgs.Tables' Error:Field validation for 'Tables' failed on the 'required' tag")
+		_, err = args.GetDSN()
+		assert.EqualError(t, err, "Key: 'Connection.Port' Error:Field validation for 'Port' failed on the 'number' tag")
 
-		args.Port = "3306"
+		args.Connection.Port = "3306"
+		args.Scale = 1
 		err = validate.Struct(args)
 		assert.EqualError(t, err, "Key: 'RunArgs.Tables' Error:Field validation for 'Tables' failed on the 'required' tag")
 
@@ -49,35 +62,329 @@ func TestRunArgs(t *testing.T) {
 
 	t.Run("test GetDSN", func(t *testing.T) {
 		args := RunArgs{
-			Database: "example",
-			Host:     "localhost",
-			Password: "root",
-			Port:     "3306",
-			Safe:     false,
-			Tables:   []string{"users.yaml", "accounts.yaml"},
-			User:     "root",
+			Connection: conn,
+			Tables:     []string{"users.yaml", "accounts.yaml"},
 		}
-		assert.Equal(t, args.GetDSN(), "root:root@tcp(localhost:3306)/example?parseTime=true&interpolateParams=true")
+		dsn, err := args.GetDSN()
+		assert.NoError(t, err)
+		assert.Equal(t, "root:root@tcp(localhost:3306)/example?interpolateParams=true&parseTime=true", dsn)
+	})
+
+	t.Run("test GetDSN defaults", func(t *testing.T) {
+		args := RunArgs{Connection: Connection{Database: "example"}}
+		dsn, err := args.GetDSN()
+		assert.NoError(t, err)
+		assert.Equal(t, "root@tcp(localhost:3306)/example?interpolateParams=true&parseTime=true", dsn)
+
+		args = RunArgs{}
+		_, err = args.GetDSN()
+		assert.EqualError(t, err, "Key: 'Connection.Database' Error:Field validation for 'Database' failed on the 'required_without' tag")
+	})
+
+	t.Run("test run config file", func(t *testing.T) {
+		t.Setenv("MYSQL_PWD", "secret")
+		cfgPath := path.Join(t.TempDir(), "run.yaml")
+		err := os.WriteFile(cfgPath, []byte("Connection:\n  Host: db.local\n  Port: 3307\n  Database: ${DB:-fromfile}\n"), 0600)
+		assert.NoError(t, err)
+		args := RunArgs{ConfigFile: cfgPath, Connection: Connection{Port: "3308"}}
+		dsn, err := args.GetDSN()
+		assert.NoError(t, err)
+		assert.Equal(t, "root:secret@tcp(db.local:3308)/fromfile?interpolateParams=true&parseTime=true", dsn)
 	})
 }
 
 func TestLoadConfig(t *testing.T) {
+	isolateConnection(t)
 	currWd, err := os.Getwd()
 	assert.NoError(t, err)
 	cfgPath := path.Join(currWd, "../../test/testdata/config-example.yaml")
 
 	args := RunArgs{
-		Database: "example",
-		Host:     "localhost",
-		Password: "root",
-		Port:     "3306",
-		Safe:     false,
-		Tables:   []string{cfgPath},
-		User:     "root",
+		Connection: Connection{
+			Database: "example",
+			Host:     "localhost",
+			Password: "root",
+			Port:     "3306",
+			User:     "root",
+		},
+		Tables: []string{cfgPath},
 	}
 
-	_, defs, err := LoadConfig(args)
+	run, err := LoadConfig(args)
 	assert.NoError(t, err)
+	defs := run.Tables
 	assert.False(t, defs[0].SafeImport) // zero value
 	assert.Equal(t, 5031, defs[0].TotalRecords)
+	assert.Equal(t, []string{"user_id", "txid", "btc"}, defs[0].Columns.Names()[:3])
+
+	args.Scale = 0.1
+	run, err = LoadConfig(args)
+	assert.NoError(t, err)
+	defs = run.Tables
+	assert.Equal(t, 503, defs[0].TotalRecords)
+	assert.Equal(t, 503, defs[0].BatchSize)
+}
+
+func TestLoadConfigManifest(t *testing.T) {
+	isolateConnection(t)
+	currWd, err := os.Getwd()
+	assert.NoError(t, err)
+	args := RunArgs{
+		ConfigFile: path.Join(currWd, "../../test/testdata/syndi-example.yaml"),
+	}
+
+	run, err := LoadConfig(args)
+	assert.NoError(t, err)
+	assert.Equal(t, "syndi:pwd@tcp(db.local:3306)/example?interpolateParams=true&parseTime=true", run.DSN)
+	assert.Equal(t, int64(42), run.Seed)
+	assert.Equal(t, []string{"SET unique_checks=0"}, run.Hooks.Before)
+	assert.Len(t, run.Tables, 2)
+	assert.Equal(t, 100, run.Tables[0].TotalRecords) // overridden by the manifest and scaled
+	assert.Equal(t, 50, run.Tables[0].BatchSize)
+	assert.Equal(t, []string{"ANALYZE TABLE account_walletdeposit"}, run.Tables[0].Hooks.After)
+	assert.Equal(t, 503, run.Tables[1].TotalRecords)
+	assert.Equal(t, 503, run.Tables[1].BatchSize)
+	assert.True(t, run.Tables[1].SafeImport)
+
+	t.Run("flags take precedence", func(t *testing.T) {
+		args := args
+		args.Seed = 7
+		args.Scale = 1
+		assert.NoError(t, args.Safe.Set("false"))
+		args.Tables = []string{path.Join(currWd, "../../test/testdata/config-example.yaml")}
+		run, err := LoadConfig(args)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), run.Seed)
+		assert.Len(t, run.Tables, 1)
+		assert.Equal(t, 5031, run.Tables[0].TotalRecords)
+		assert.False(t, run.Tables[0].SafeImport)
+	})
+}
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("SYNDI_TEST_USERS", "1000")
+	t.Setenv("SYNDI_TEST_EMPTY", "")
+	interpolate := func(data string, vars Vars) (map[string]interface{}, error) {
+		res, err := Interpolate([]byte(data), vars)
+		if err != nil {
+			return nil, err
+		}
+		doc := map[string]interface{}{}
+		return doc, yaml.Unmarshal(res, &doc)
+	}
+
+	t.Run("environment and defaults", func(t *testing.T) {
+		doc, err := interpolate("TotalRecords: ${SYNDI_TEST_USERS}\nBatchSize: ${SYNDI_TEST_BATCH:-100}\n", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"TotalRecords": 1000, "BatchSize": 100}, doc)
+	})
+
+	t.Run("vars take precedence over environment", func(t *testing.T) {
+		vars := Vars{}
+		assert.NoError(t, vars.Set("SYNDI_TEST_USERS=5"))
+		assert.NoError(t, vars.Set("END_DATE=2021-12-01 21:54:35"))
+		doc, err := interpolate("TotalRecords: ${SYNDI_TEST_USERS:-1}\nMaxVal: ${END_DATE}", vars)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"TotalRecords": 5, "MaxVal": "2021-12-01 21:54:35"}, doc)
+	})
+
+	t.Run("empty values", func(t *testing.T) {
+		doc, err := interpolate("a: ${SYNDI_TEST_EMPTY}|${SYNDI_TEST_EMPTY:-x}", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"a": "|x"}, doc)
+	})
+
+	t.Run("escaped", func(t *testing.T) {
+		doc, err := interpolate("a: $${SYNDI_TEST_MISSING}", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"a": "${SYNDI_TEST_MISSING}"}, doc)
+	})
+
+	t.Run("undefined variables", func(t *testing.T) {
+		_, err := interpolate("a: ${SYNDI_TEST_A} ${SYNDI_TEST_B}\nb: [x, '${SYNDI_TEST_A}']", nil)
+		assert.EqualError(t, err, "undefined variables: SYNDI_TEST_A, SYNDI_TEST_B")
+	})
+
+	t.Run("comments and special characters", func(t *testing.T) {
+		vars := Vars{"NOTE": "a: b # not a comment\nc: d"}
+		data := "# Uses ${SYNDI_TEST_UNDEFINED} in a comment.\nHooks:\n  Before:\n    - SET @note = '${NOTE}' # ${SYNDI_TEST_UNDEFINED}\n"
+		doc, err := interpolate(data, vars)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"Hooks": map[interface{}]interface{}{
+			"Before": []interface{}{"SET @note = 'a: b # not a comment\nc: d'"},
+		}}, doc)
+	})
+
+	t.Run("invalid var flag", func(t *testing.T) {
+		vars := Vars{}
+		assert.Error(t, vars.Set("novalue"))
+		assert.Error(t, vars.Set("=value"))
+	})
+}
+
+func TestTableDefScale(t *testing.T) {
+	fixed := 1.0
+	half := 0.5
+	tests := []struct {
+		name      string
+		total     int
+		scaleWith *float64
+		factor    float64
+		expected  int
+	}{
+		{"no scaling", 1000, nil, 0, 1000},
+		{"scale down", 1000, nil, 0.01, 10},
+		{"scale up", 1000, nil, 10, 10000},
+		{"rounding", 5031, nil, 0.01, 50},
+		{"never zero", 10, nil, 0.001, 1},
+		{"fixed size", 1000, &fixed, 10, 1000},
+		{"own factor", 1000, &half, 10, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tdef := TableDef{TotalRecords: tt.total, ScaleWith: tt.scaleWith}
+			tdef.Scale(tt.factor)
+			assert.Equal(t, tt.expected, tdef.TotalRecords)
+		})
+	}
+}
+
+func TestColumnsOrder(t *testing.T) {
+	tdef := TableDef{}
+	err := yaml.Unmarshal([]byte(`
+Columns:
+  zeta:
+    Type: int
+  alpha:
+    Type: string
+  mid:
+    Type: bool
+`), &tdef)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"zeta", "alpha", "mid"}, tdef.Columns.Names())
+	assert.Equal(t, "string", tdef.Columns[1].Type)
+
+	out, err := yaml.Marshal(tdef.Columns)
+	assert.NoError(t, err)
+	cols := Columns{}
+	assert.NoError(t, yaml.Unmarshal(out, &cols))
+	assert.Equal(t, tdef.Columns, cols)
+
+	err = yaml.Unmarshal([]byte("Columns:\n  a:\n    Type: int\n  a:\n    Type: bool\n"), &tdef)
+	assert.EqualError(t, err, `column "a" is defined more than once`)
+}
+
+func TestConflictDef(t *testing.T) {
+	tdef := TableDef{}
+	assert.NoError(t, yaml.Unmarshal([]byte("OnConflict: ignore\n"), &tdef))
+	assert.Equal(t, ConflictDef{Action: ConflictIgnore}, tdef.OnConflict)
+
+	tdef = TableDef{}
+	assert.NoError(t, yaml.Unmarshal([]byte("OnConflict:\n  Action: update\n  Key: [id]\n  Update: [a, b]\n"), &tdef))
+	assert.Equal(t, ConflictDef{Action: ConflictUpdate, Key: []string{"id"}, Update: []string{"a", "b"}},
+		tdef.OnConflict)
+
+	out, err := yaml.Marshal(TableDef{TableName: "t"})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(out), "OnConflict")
+
+	assert.Error(t, validator.New().Struct(ConflictDef{Action: "merge"}))
+}
+
+func TestLoadConfigMask(t *testing.T) {
+	isolateConnection(t)
+	currWd, err := os.Getwd()
+	assert.NoError(t, err)
+	args := RunArgs{
+		Connection: Connection{Database: "example"},
+		Scale:      0.1,
+		Tables:     []string{path.Join(currWd, "../../test/testdata/mask-example.yaml")},
+		Vars:       Vars{"MASK_SECRET": "s3cret"},
+	}
+
+	run, err := LoadConfig(args)
+	assert.NoError(t, err)
+	tdef := run.Tables[0]
+	assert.Equal(t, &MaskDef{Source: "account_walletdeposit", Secret: "s3cret"}, tdef.Mask)
+	assert.Equal(t, "account_walletdeposit", tdef.SourceTable())
+	assert.Equal(t, 0, tdef.TotalRecords) // Not needed, so not scaled either.
+	assert.Equal(t, 1000, tdef.BatchSize)
+
+	tdef.Mask.Source = ""
+	assert.Equal(t, "account_walletdeposit_masked", tdef.SourceTable())
+
+	args.Vars["MASK_SECRET"] = ""
+	_, err = LoadConfig(args)
+	assert.Error(t, err)
+}
+
+func TestParseRate(t *testing.T) {
+	for s, want := range map[string]float64{"500/s": 500, "120/m": 2, "1800/h": 0.5, "2.5/s": 2.5} {
+		got, err := ParseRate(s)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, s)
+	}
+	for _, s := range []string{"500", "0/s", "-1/s", "x/s", "5/d"} {
+		_, err := ParseRate(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestResolveRefs(t *testing.T) {
+	users := &TableDef{TableName: "users", TotalRecords: 50, Columns: Columns{
+		{Name: "id", ColumnDef: ColumnDef{Type: "int/incremental-uniform", First: "100", MinVal: "1", MaxVal: "2"}},
+		{Name: "status", ColumnDef: ColumnDef{Type: "string/oneof", OneOf: "new;active"}},
+		{Name: "name", ColumnDef: ColumnDef{Type: "string/rand"}},
+	}}
+	orders := &TableDef{TableName: "orders", TotalRecords: 500, Columns: Columns{
+		{Name: "user_id", ColumnDef: ColumnDef{Type: "int/uniform", MinVal: "1", MaxVal: "10", Nullable: 0.1,
+			Ref: "users.id"}},
+		{Name: "user_status", ColumnDef: ColumnDef{Type: "string/rand", Ref: "users.status"}},
+		{Name: "currency_id", ColumnDef: ColumnDef{Type: "int/oneof", OneOf: "1;2", Ref: "currency.id"}},
+	}}
+	assert.NoError(t, resolveRefs([]*TableDef{users, orders}))
+	assert.Equal(t, ColumnDef{Type: "int/uniform", MinVal: "100", MaxVal: "150", Nullable: 0.1, Ref: "users.id"},
+		orders.Columns[0].ColumnDef)
+	assert.Equal(t, ColumnDef{Type: "string/oneof", OneOf: "new;active", Ref: "users.status"}, orders.Columns[1].ColumnDef)
+	// The referenced table isn't generated by the run, so the values in the database are used.
+	assert.Equal(t, ColumnDef{Type: "int/oneof", OneOf: "1;2", Ref: "currency.id"}, orders.Columns[2].ColumnDef)
+
+	orders.Columns[2].Ref = "users.name"
+	assert.EqualError(t, resolveRefs([]*TableDef{users, orders}), "orders.currency_id: referenced column users.name "+
+		"has to be a oneof or int/incremental-uniform with MinVal 1 and MaxVal 2")
+	orders.Columns[2].Ref = "users"
+	assert.EqualError(t, resolveRefs([]*TableDef{users, orders}),
+		`orders.currency_id: Ref "users" should be written as table.column`)
+}
+
+func TestLoadConfigScalesRefs(t *testing.T) {
+	isolateConnection(t)
+	dir := t.TempDir()
+	users, orders := path.Join(dir, "users.yaml"), path.Join(dir, "orders.yaml")
+	assert.NoError(t, os.WriteFile(users, []byte(`TableName: users
+TotalRecords: 1000
+BatchSize: 100
+Columns:
+  id:
+    Type: int/incremental-uniform
+    First: 1
+    MinVal: 1
+    MaxVal: 2
+`), 0600))
+	assert.NoError(t, os.WriteFile(orders, []byte(`TableName: orders
+TotalRecords: 5000
+BatchSize: 100
+Columns:
+  user_id:
+    Type: int/uniform
+    MinVal: 1
+    MaxVal: 1001
+    Ref: users.id
+`), 0600))
+	args := RunArgs{Connection: Connection{Database: "example"}, Scale: 0.1, Tables: []string{users, orders}}
+
+	run, err := LoadConfig(args)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, run.Tables[0].TotalRecords)
+	assert.Equal(t, "101", run.Tables[1].Columns[0].MaxVal, "references only the scaled users")
 }
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// DefaultOptionFile is the MySQL option file that is read (if it exists) when no other option file is given.
+const DefaultOptionFile = "~/.my.cnf"
+
+// tlsConfigName is the name under which the TLS config is registered with the MySQL driver.
+const tlsConfigName = "syndi"
+
+// Connection describes how to connect to the database. Settings are collected from several sources, in order of
+// precedence: command-line flags, environment variables, the `Connection` section of a run config file and a MySQL
+// option file (e.g. ~/.my.cnf). A full DSN hides the settings it contains in its own and all the following sources, but
+// the ones of preceding sources override its parts.
+type Connection struct {
+	DSN      string `yaml:"DSN"`
+	Host     string `yaml:"Host"`
+	Port     string `yaml:"Port" validate:"omitempty,number,gt=0"`
+	Socket   string `yaml:"Socket"`
+	User     string `yaml:"User"`
+	Password string `yaml:"Password"`
+	Database string `yaml:"Database" validate:"required_without=DSN"`
+	SSLCA    string `yaml:"SSLCA"`
+	SSLCert  string `yaml:"SSLCert" validate:"required_with=SSLKey"`
+	SSLKey   string `yaml:"SSLKey" validate:"required_with=SSLCert"`
+}
+
+// DefaultConnection holds the values used for settings that weren't given by any other source.
+var DefaultConnection = Connection{
+	Host: "localhost",
+	Port: "3306",
+	User: "root",
+}
+
+// Merge fills the settings that are still empty with the ones from other, a source of lower precedence. The server
+// address is taken from a single source: a host or port hides the socket of other and a socket hides its host and port.
+func (c *Connection) Merge(other Connection) {
+	if c.DSN != "" || other.DSN != "" {
+		other.Host, other.Port, other.Socket, other.User, other.Password, other.Database = "", "", "", "", "", ""
+	}
+	if c.Host != "" || c.Port != "" {
+		other.Socket = ""
+	}
+	if c.Socket != "" {
+		other.Host, other.Port = "", ""
+	}
+	mergeStr(&c.DSN, other.DSN)
+	mergeStr(&c.Host, other.Host)
+	mergeStr(&c.Port, other.Port)
+	mergeStr(&c.Socket, other.Socket)
+	mergeStr(&c.User, other.User)
+	mergeStr(&c.Password, other.Password)
+	mergeStr(&c.Database, other.Database)
+	mergeStr(&c.SSLCA, other.SSLCA)
+	mergeStr(&c.SSLCert, other.SSLCert)
+	mergeStr(&c.SSLKey, other.SSLKey)
+}
+
+func mergeStr(dst *string, src string) {
+	if *dst == "" {
+		*dst = src
+	}
+}
+
+// ConnectionFromEnv reads connection settings from `SYNDI_DSN` and the environment variables used by MySQL clients
+// (`MYSQL_HOST`, `MYSQL_TCP_PORT`, `MYSQL_UNIX_PORT` and `MYSQL_PWD`).
+func ConnectionFromEnv() Connection {
+	return Connection{
+		DSN:      os.Getenv("SYNDI_DSN"),
+		Host:     os.Getenv("MYSQL_HOST"),
+		Port:     os.Getenv("MYSQL_TCP_PORT"),
+		Socket:   os.Getenv("MYSQL_UNIX_PORT"),
+		Password: os.Getenv("MYSQL_PWD"),
+	}
+}
+
+// ReadOptionFile reads connection settings from the `[client]` and `[syndi]` groups of a MySQL option file, with the
+// latter taking precedence.
+func ReadOptionFile(path string) (Connection, error) {
+	f, err := os.Open(expandHome(path))
+	if err != nil {
+		return Connection{}, err
+	}
+	defer f.Close()
+
+	groups := map[string]*Connection{"client": {}, "syndi": {}}
+	var conn *Connection
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' || line[0] == '!' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			conn = groups[strings.TrimSpace(line[1:len(line)-1])]
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if conn == nil || len(parts) == 1 {
+			continue
+		}
+		val := strings.TrimSpace(parts[1])
+		if len(val) >= 2 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0] {
+			val = val[1 : len(val)-1]
+		}
+		// Like in MySQL, options can be spelled with dashes or underscores and later values win.
+		switch strings.ReplaceAll(strings.TrimSpace(parts[0]), "_", "-") {
+		case "host":
+			conn.Host = val
+		case "port":
+			conn.Port = val
+		case "socket":
+			conn.Socket = val
+		case "user":
+			conn.User = val
+		case "password":
+			conn.Password = val
+		case "database":
+			conn.Database = val
+		case "ssl-ca":
+			conn.SSLCA = val
+		case "ssl-cert":
+			conn.SSLCert = val
+		case "ssl-key":
+			conn.SSLKey = val
+		}
+	}
+	res := *groups["syndi"]
+	res.Merge(*groups["client"])
+	return res, scanner.Err()
+}
+
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
+
+// GetDSN builds a DSN for the MySQL driver. Passing SSL files registers a TLS config with the driver as a side effect.
+func (c Connection) GetDSN() (string, error) {
+	cfg := mysql.NewConfig()
+	if c.DSN != "" {
+		parsed, err := mysql.ParseDSN(c.DSN)
+		if err != nil {
+			return "", fmt.Errorf("invalid DSN: %w", err)
+		}
+		cfg = parsed
+	}
+	// Settings next to a DSN come from sources of higher precedence (see Merge).
+	mergeStr(&c.User, cfg.User)
+	mergeStr(&c.Password, cfg.Passwd)
+	mergeStr(&c.Database, cfg.DBName)
+	cfg.User, cfg.Passwd, cfg.DBName = c.User, c.Password, c.Database
+	if c.Socket != "" {
+		cfg.Net = "unix"
+		cfg.Addr = c.Socket
+	} else if c.Host != "" || c.Port != "" {
+		if cfg.Net == "tcp" {
+			if host, port, err := net.SplitHostPort(cfg.Addr); err == nil {
+				mergeStr(&c.Host, host)
+				mergeStr(&c.Port, port)
+			}
+		}
+		mergeStr(&c.Host, DefaultConnection.Host)
+		mergeStr(&c.Port, DefaultConnection.Port)
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(c.Host, c.Port)
+	}
+	cfg.ParseTime = true
+	cfg.InterpolateParams = true
+
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return "", err
+	}
+	if tlsConfig != nil {
+		if err := mysql.RegisterTLSConfig(tlsConfigName, tlsConfig); err != nil {
+			return "", err
+		}
+		cfg.TLSConfig = tlsConfigName
+	}
+	return cfg.FormatDSN(), nil
+}
+
+func (c Connection) tlsConfig() (*tls.Config, error) {
+	if c.SSLCA == "" && c.SSLCert == "" {
+		return nil, nil
+	}
+	conf := &tls.Config{}
+	if c.SSLCA != "" {
+		pem, err := ioutil.ReadFile(expandHome(c.SSLCA))
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.SSLCA)
+		}
+		conf.RootCAs = pool
+	}
+	if c.SSLCert != "" {
+		cert, err := tls.LoadX509KeyPair(expandHome(c.SSLCert), expandHome(c.SSLKey))
+		if err != nil {
+			return nil, err
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	return conf, nil
+}
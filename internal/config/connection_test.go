@@ -0,0 +1,112 @@
+package config
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnectionMerge(t *testing.T) {
+	c := Connection{Host: "flag-host"}
+	c.Merge(Connection{Host: "env-host", Password: "env-pwd"})
+	c.Merge(Connection{Password: "file-pwd", Database: "file-db"})
+	assert.Equal(t, Connection{Host: "flag-host", Password: "env-pwd", Database: "file-db"}, c)
+}
+
+func TestConnectionMergePrecedence(t *testing.T) {
+	t.Run("host hides a socket of a lower source", func(t *testing.T) {
+		c := Connection{Host: "flag-host"}
+		c.Merge(Connection{Socket: "/env.sock"})
+		c.Merge(DefaultConnection)
+		assert.Equal(t, Connection{Host: "flag-host", Port: "3306", User: "root"}, c)
+	})
+
+	t.Run("socket hides a host of a lower source", func(t *testing.T) {
+		c := Connection{Socket: "/flag.sock"}
+		c.Merge(Connection{Host: "env-host", Port: "3307"})
+		c.Merge(DefaultConnection)
+		assert.Equal(t, Connection{Socket: "/flag.sock", User: "root"}, c)
+	})
+
+	t.Run("DSN hides settings of its own and lower sources", func(t *testing.T) {
+		c := Connection{Host: "flag-host", Database: "flag-db"}
+		c.Merge(Connection{DSN: "app:pwd@unix(/env.sock)/env-db?timeout=5s", Password: "env-pwd"})
+		c.Merge(DefaultConnection)
+		assert.Equal(t, Connection{DSN: "app:pwd@unix(/env.sock)/env-db?timeout=5s", Host: "flag-host",
+			Database: "flag-db"}, c)
+		dsn, err := c.GetDSN()
+		assert.NoError(t, err)
+		assert.Equal(t, "app:pwd@tcp(flag-host:3306)/flag-db?interpolateParams=true&parseTime=true&timeout=5s", dsn)
+	})
+}
+
+func TestReadOptionFile(t *testing.T) {
+	cnfPath := path.Join(t.TempDir(), "my.cnf")
+	err := os.WriteFile(cnfPath, []byte(`
+# comment
+[mysqld]
+port = 1111
+
+[client]
+user = client_user
+password = "quoted pwd"
+port=3307
+ssl_ca = /etc/ca.pem
+
+[syndi]
+user = syndi_user
+database = syndi_db
+!includedir /etc/mysql/conf.d/
+`), 0600)
+	assert.NoError(t, err)
+
+	c, err := ReadOptionFile(cnfPath)
+	assert.NoError(t, err)
+	assert.Equal(t, Connection{
+		User:     "syndi_user",
+		Password: "quoted pwd",
+		Port:     "3307",
+		Database: "syndi_db",
+		SSLCA:    "/etc/ca.pem",
+	}, c)
+
+	_, err = ReadOptionFile(path.Join(t.TempDir(), "missing.cnf"))
+	assert.Error(t, err)
+}
+
+func TestConnectionGetDSN(t *testing.T) {
+	t.Run("unix socket", func(t *testing.T) {
+		c := Connection{Socket: "/var/run/mysqld/mysqld.sock", User: "app", Database: "db"}
+		dsn, err := c.GetDSN()
+		assert.NoError(t, err)
+		assert.Equal(t, "app@unix(/var/run/mysqld/mysqld.sock)/db?interpolateParams=true&parseTime=true", dsn)
+	})
+
+	t.Run("full DSN", func(t *testing.T) {
+		c := Connection{DSN: "app:pwd@tcp(db:3306)/other?timeout=5s"}
+		dsn, err := c.GetDSN()
+		assert.NoError(t, err)
+		assert.Equal(t, "app:pwd@tcp(db:3306)/other?interpolateParams=true&parseTime=true&timeout=5s", dsn)
+	})
+
+	t.Run("DSN with overrides", func(t *testing.T) {
+		c := Connection{DSN: "app:pwd@tcp(db:3306)/other?timeout=5s", Port: "3307", Database: "db"}
+		dsn, err := c.GetDSN()
+		assert.NoError(t, err)
+		assert.Equal(t, "app:pwd@tcp(db:3307)/db?interpolateParams=true&parseTime=true&timeout=5s", dsn)
+	})
+
+	t.Run("invalid DSN", func(t *testing.T) {
+		c := Connection{DSN: "app:pwd@tcp(db:3306)"}
+		_, err := c.GetDSN()
+		assert.Error(t, err)
+	})
+
+	t.Run("missing CA file", func(t *testing.T) {
+		c := Connection{Host: "db", Port: "3306", Database: "db", SSLCA: path.Join(t.TempDir(), "ca.pem")}
+		_, err := c.GetDSN()
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Vars holds variables that can be referenced in YAML configs as `${NAME}` or `${NAME:-default}`.
+// It implements flag.Value, so it can be filled from repeated `-var key=value` command-line flags.
+type Vars map[string]string
+
+func (v Vars) String() string {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+v[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (v *Vars) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return fmt.Errorf("variable should be given as key=value, got %q", s)
+	}
+	if *v == nil {
+		*v = Vars{}
+	}
+	(*v)[s[:i]] = s[i+1:]
+	return nil
+}
+
+// varPattern matches `${NAME}`, `${NAME:-default}` and their escaped `$${...}` variants.
+var varPattern = regexp.MustCompile(`\$(\$?)\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// Interpolate replaces variable references in the string values of the YAML document. Variables passed in vars take
+// precedence over the environment. Like in a shell, the default value is used when a variable is either unset or
+// empty. A reference can be escaped by doubling the dollar sign (`$${NAME}` becomes a literal `${NAME}`). All undefined
+// variables without a default are reported in a single error.
+//
+// The document is parsed first, so references in comments and keys are left alone and values containing `:`, `#` or
+// newlines can't change its structure. A value that is a number or a boolean after interpolation is written as one,
+// so `TotalRecords: ${USERS}` still works.
+func Interpolate(data []byte, vars Vars) ([]byte, error) {
+	if !varPattern.Match(data) {
+		return data, nil
+	}
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	i := interpolator{vars: vars, seen: map[string]bool{}}
+	res := i.value(doc)
+	if len(i.undefined) > 0 {
+		return nil, fmt.Errorf("undefined variables: %s", strings.Join(i.undefined, ", "))
+	}
+	return yaml.Marshal(res)
+}
+
+type interpolator struct {
+	vars      Vars
+	seen      map[string]bool
+	undefined []string
+}
+
+// value interpolates the strings in v, recursing into mappings and sequences.
+func (i *interpolator) value(v interface{}) interface{} {
+	switch val := v.(type) {
+	case yaml.MapSlice:
+		for j := range val {
+			val[j].Value = i.value(val[j].Value)
+		}
+	case []interface{}:
+		for j := range val {
+			val[j] = i.value(val[j])
+		}
+	case string:
+		if !varPattern.MatchString(val) {
+			return val
+		}
+		res := varPattern.ReplaceAllStringFunc(val, i.replace)
+		var resolved interface{}
+		if err := yaml.Unmarshal([]byte(res), &resolved); err == nil {
+			switch resolved.(type) {
+			case int, int64, uint64, float64, bool:
+				return resolved
+			}
+		}
+		return res
+	}
+	return v
+}
+
+func (i *interpolator) replace(match string) string {
+	m := varPattern.FindStringSubmatch(match)
+	if m[1] != "" {
+		return match[1:]
+	}
+	name := m[2]
+	val, ok := i.vars[name]
+	if !ok {
+		val, ok = os.LookupEnv(name)
+	}
+	if val != "" {
+		return val
+	}
+	if m[3] != "" {
+		return m[4]
+	}
+	if ok {
+		return ""
+	}
+	if !i.seen[name] {
+		i.seen[name] = true
+		i.undefined = append(i.undefined, name)
+	}
+	return match
+}
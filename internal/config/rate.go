@@ -0,0 +1,28 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseRate parses rates such as 500/s, 3000/m or 100000/h into events per second.
+func ParseRate(s string) (float64, error) {
+	i := strings.LastIndexByte(s, '/')
+	if i < 0 {
+		return 0, fmt.Errorf("rate %q has no unit, use e.g. 500/s", s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("rate %q is not a positive number", s)
+	}
+	switch s[i+1:] {
+	case "s":
+		return n, nil
+	case "m":
+		return n / 60, nil
+	case "h":
+		return n / 3600, nil
+	}
+	return 0, fmt.Errorf("rate %q has an unknown unit, use one of: s, m, h", s)
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// resolveRefs replaces the generators of columns with a Ref to a table of the same run with ones picking the values
+// generated for the referenced column. Refs to other tables keep the generator of the column.
+func resolveRefs(tables []*TableDef) error {
+	byName := make(map[string]*TableDef, len(tables))
+	for _, t := range tables {
+		byName[t.TableName] = t
+	}
+	for _, t := range tables {
+		for i := range t.Columns {
+			col := &t.Columns[i]
+			if col.Ref == "" {
+				continue
+			}
+			def, err := refDef(byName, col.Ref)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", t.TableName, col.Name, err)
+			}
+			if def != nil {
+				def.Nullable, def.Format, def.SQLType, def.Ref = col.Nullable, col.Format, col.SQLType, col.Ref
+				col.ColumnDef = *def
+			}
+		}
+	}
+	return nil
+}
+
+// refDef returns the definition of a generator picking the values of the referenced `table.column`, or nil if the
+// table isn't in the run. Only values that are known upfront can be referenced: consecutive incremental integers
+// (MinVal 1 and MaxVal 2) and oneof values.
+func refDef(tables map[string]*TableDef, ref string) (*ColumnDef, error) {
+	i := strings.LastIndexByte(ref, '.')
+	if i <= 0 || i == len(ref)-1 {
+		return nil, fmt.Errorf("Ref %q should be written as table.column", ref)
+	}
+	parent, ok := tables[ref[:i]]
+	if !ok {
+		return nil, nil
+	}
+	j := parent.Columns.Index(ref[i+1:])
+	if j < 0 {
+		return nil, fmt.Errorf("referenced column %s isn't generated", ref)
+	}
+	def := parent.Columns[j].ColumnDef
+	if strings.HasSuffix(def.Type, "oneof") {
+		return &ColumnDef{Type: def.Type, OneOf: def.OneOf}, nil
+	}
+	first, err := strconv.ParseInt(def.First, 10, 64)
+	if def.Type != "int/incremental-uniform" || def.MinVal != "1" || def.MaxVal != "2" || err != nil ||
+		parent.Mask != nil {
+		return nil, fmt.Errorf("referenced column %s has to be a oneof or int/incremental-uniform with MinVal 1 and "+
+			"MaxVal 2", ref)
+	}
+	return &ColumnDef{Type: "int/uniform", MinVal: def.First,
+		MaxVal: strconv.FormatInt(first+int64(parent.TotalRecords), 10)}, nil
+}
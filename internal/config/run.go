@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// RunConfig holds the settings from the run config file. Besides the connection it can describe a complete dataset
+// (a manifest), so that `syndi run syndi.yaml` reproduces it without any other arguments.
+type RunConfig struct {
+	Connection Connection `yaml:"Connection" validate:"-"` // Validated once merged with other sources.
+	Seed       int64      `yaml:"Seed"`                    // Makes generated data reproducible, zero means a random seed.
+	Scale      float64    `yaml:"Scale" validate:"omitempty,gt=0"`
+	Safe       bool       `yaml:"Safe"`
+	Vars       Vars       `yaml:"Vars"`     // Variables for table definitions, -var flags take precedence.
+	Defaults   TableRef   `yaml:"Defaults"` // Used for settings missing in table definitions.
+	Tables     []TableRef `yaml:"Tables" validate:"dive"`
+	Hooks      Hooks      `yaml:"Hooks"` // Run before the first and after the last table.
+}
+
+// TableRef points to a table definition file and overrides some of its settings. Relative paths are resolved
+// against the directory of the run config file. Tables are imported in the order they are listed.
+type TableRef struct {
+	File         string `yaml:"File"`
+	TotalRecords int    `yaml:"TotalRecords" validate:"omitempty,gt=0"`
+	BatchSize    int    `yaml:"BatchSize" validate:"omitempty,gt=0"`
+	BatchBytes   int    `yaml:"BatchBytes" validate:"omitempty,gt=0"`
+	Hooks        Hooks  `yaml:"Hooks"` // Appended to the hooks of the table definition.
+}
+
+// apply overrides the settings in tdef and fills the missing ones from defaults.
+func (r TableRef) apply(tdef *TableDef, defaults TableRef) {
+	if r.TotalRecords > 0 {
+		tdef.TotalRecords = r.TotalRecords
+	} else if tdef.TotalRecords == 0 {
+		tdef.TotalRecords = defaults.TotalRecords
+	}
+	if r.BatchSize > 0 {
+		tdef.BatchSize = r.BatchSize
+	} else if tdef.BatchSize == 0 {
+		tdef.BatchSize = defaults.BatchSize
+	}
+	if r.BatchBytes > 0 {
+		tdef.BatchBytes = r.BatchBytes
+	} else if tdef.BatchBytes == 0 {
+		tdef.BatchBytes = defaults.BatchBytes
+	}
+	tdef.Hooks.Before = append(tdef.Hooks.Before, r.Hooks.Before...)
+	tdef.Hooks.After = append(tdef.Hooks.After, r.Hooks.After...)
+}
+
+// Hooks are SQL statements executed before and after importing data.
+type Hooks struct {
+	Before []string `yaml:"Before,omitempty"`
+	After  []string `yaml:"After,omitempty"`
+}
+
+// Run is everything needed to import a dataset.
+type Run struct {
+	DSN    string
+	Seed   int64
+	Hooks  Hooks
+	Tables []*TableDef
+}
+
+// LoadRunConfig loads the run config file, interpolating variables the same way as in table definitions.
+func LoadRunConfig(path string, vars Vars) (*RunConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err = Interpolate(data, vars)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	rc := &RunConfig{}
+	err = yaml.Unmarshal(data, rc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	for i, ref := range rc.Tables {
+		if ref.File == "" {
+			return nil, fmt.Errorf("%s: table #%d has no File", path, i+1)
+		}
+		if !filepath.IsAbs(ref.File) {
+			rc.Tables[i].File = filepath.Join(dir, ref.File)
+		}
+	}
+	return rc, nil
+}
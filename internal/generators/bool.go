@@ -5,6 +5,10 @@ import (
 )
 
 func NewBoolGenerator(args config.ColumnDef) Generator {
+	return newBoolGenerator(args, defaultSource)
+}
+
+func newBoolGenerator(args config.ColumnDef, src source) Generator {
 	args.OneOf = "0;1"
-	return NewOneOfGenerator(args)
+	return newIntOneOfGenerator(args, src)
 }
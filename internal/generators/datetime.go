@@ -3,61 +3,414 @@ package generators
 import (
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bitstonks/syndi/internal/config"
 )
 
+// timeNow is the time relative bounds like `now` or `-30d` are based on, patched in tests.
+var timeNow = time.Now
+
 func NewDatetimeNowGenerator(args config.ColumnDef) Generator {
-	args.OneOf = "NOW()"
-	return NewOneOfGenerator(args)
+	return newDatetimeNowGenerator(args, defaultSource)
+}
+
+func newDatetimeNowGenerator(args config.ColumnDef, src source) Generator {
+	return newNowGenerator(args, "NOW", src)
+}
+
+func NewDateNowGenerator(args config.ColumnDef) Generator {
+	return newDateNowGenerator(args, defaultSource)
+}
+
+func newDateNowGenerator(args config.ColumnDef, src source) Generator {
+	return newNowGenerator(args, "CURDATE", src)
+}
+
+func NewTimeNowGenerator(args config.ColumnDef) Generator {
+	return newTimeNowGenerator(args, defaultSource)
+}
+
+func newTimeNowGenerator(args config.ColumnDef, src source) Generator {
+	return newNowGenerator(args, "CURTIME", src)
+}
+
+// newNowGenerator generates a call of the SQL function, with the fractional seconds digits of Precision. The database
+// evaluates it in the time zone of the session, so the options of generated times that don't apply are rejected.
+func newNowGenerator(args config.ColumnDef, fn string, src source) Generator {
+	if args.TimeZone != "" || args.Epoch != "" || args.Layout != "" {
+		log.Panicf("%s can't be used with TimeZone, Epoch or Layout", args.Type)
+	}
+	if args.Precision < 0 || args.Precision > 6 {
+		log.Panicf("Precision must be between 0 and 6, got %d", args.Precision)
+	}
+	args.OneOf = fn + "()"
+	if args.Precision > 0 {
+		if timeKindOf(args.Type).days {
+			log.Panicf("%s has no fractional seconds", args.Type)
+		}
+		args.OneOf = fmt.Sprintf("%s(%d)", fn, args.Precision)
+	}
+	return newOneOfGenerator(args, src)
+}
+
+// timeKind holds what differs between datetime, date and time generators.
+type timeKind struct {
+	layout  string // Of the output and the default of Layout.
+	days    bool   // Whether the values are whole days.
+	clock   bool   // Whether the values are times of day, which can't be relative or in epoch.
+	minVal  time.Time
+	maxStep time.Duration // Default step of incremental generators.
+}
+
+// timeKindOf returns the kind of values the generator type produces: datetime, date or time.
+func timeKindOf(genType string) timeKind {
+	switch {
+	case strings.HasPrefix(genType, "date/"):
+		return timeKind{layout: DateLayout, days: true, minVal: time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
+			maxStep: 24 * time.Hour}
+	case strings.HasPrefix(genType, "time/"):
+		return timeKind{layout: TimeLayout, clock: true, minVal: time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)}
+	}
+	return timeKind{layout: DatetimeLayout, minVal: time.Date(1970, 1, 0, 0, 0, 0, 0, time.UTC), maxStep: time.Second}
+}
+
+// timeOutput turns generated times into values: datetimes in the TimeZone, layout and Precision of the column, or
+// Unix epoch integers.
+type timeOutput struct {
+	kind   timeKind
+	loc    *time.Location
+	layout string // Input layout of First, MinVal and MaxVal.
+	out    string // Output layout including fractional seconds.
+	scale  int64  // Values per second, 10^Precision.
+	epoch  string
+	now    time.Time
+}
+
+func newTimeOutput(args config.ColumnDef) (timeOutput, error) {
+	o := timeOutput{kind: timeKindOf(args.Type), loc: time.UTC, layout: args.Layout, epoch: args.Epoch, scale: 1,
+		now: timeNow()}
+	if args.TimeZone != "" {
+		loc, err := time.LoadLocation(args.TimeZone)
+		if err != nil {
+			return o, fmt.Errorf("unknown TimeZone %q: %w", args.TimeZone, err)
+		}
+		o.loc = loc
+	}
+	if o.layout == "" {
+		o.layout = o.kind.layout
+	}
+	o.out = o.kind.layout
+	if args.Precision < 0 || args.Precision > 6 {
+		return o, fmt.Errorf("Precision must be between 0 and 6, got %d", args.Precision)
+	}
+	if args.Precision > 0 && !o.kind.days {
+		o.out += "." + strings.Repeat("0", args.Precision)
+		o.scale = int64(math.Pow10(args.Precision))
+	}
+	switch args.Epoch {
+	case "", "s", "ms", "us", "µs":
+	default:
+		return o, fmt.Errorf("unknown Epoch unit %q, use one of: s, ms, us", args.Epoch)
+	}
+	if args.Epoch != "" && o.kind.clock {
+		return o, fmt.Errorf("%s can't be written as Unix epoch", args.Type)
+	}
+	return o, nil
+}
+
+func (o timeOutput) value(t time.Time) Value {
+	switch o.epoch {
+	case "s":
+		return IntValue(t.Unix())
+	case "ms":
+		return IntValue(t.UnixMilli())
+	case "us", "µs":
+		return IntValue(t.UnixMicro())
+	}
+	return TimeLayoutValue(t.In(o.loc), o.out)
+}
+
+// parse parses a bound such as MinVal: a time in the layout, `now`, or a duration relative to now such as `-30d` or
+// `now+1h`. Dates are truncated to midnight.
+func (o timeOutput) parse(s string) (time.Time, error) {
+	rel := strings.TrimPrefix(s, "now")
+	var t time.Time
+	switch {
+	case o.kind.clock || (rel == s && !strings.HasPrefix(s, "-") && !strings.HasPrefix(s, "+")):
+		var err error
+		t, err = time.ParseInLocation(o.layout, s, o.loc)
+		if err != nil {
+			return t, fmt.Errorf("error parsing %q: %w", s, err)
+		}
+	case rel == "":
+		t = o.now
+	default:
+		d, err := ParseDuration(rel)
+		if err != nil {
+			return t, err
+		}
+		t = o.now.Add(d)
+	}
+	if o.kind.days {
+		t = t.In(o.loc)
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, o.loc)
+	}
+	return t, nil
+}
+
+// bounds returns the parsed MinVal and MaxVal, the minimum of the kind and now (or the end of the day for times) if
+// they are empty.
+func (o timeOutput) bounds(args config.ColumnDef) (time.Time, time.Time, error) {
+	minVal, maxVal := o.kind.minVal, o.now
+	if o.kind.clock {
+		maxVal = minVal.Add(24 * time.Hour)
+	} else if o.kind.days {
+		maxVal, _ = o.parse("now")
+	}
+	var err error
+	if args.MinVal != "" {
+		if minVal, err = o.parse(args.MinVal); err != nil {
+			return minVal, maxVal, err
+		}
+	}
+	if args.MaxVal != "" {
+		if maxVal, err = o.parse(args.MaxVal); err != nil {
+			return minVal, maxVal, err
+		}
+	}
+	if !minVal.Before(maxVal) {
+		return minVal, maxVal, fmt.Errorf("minVal not smaller than maxVal: %s < %s", minVal.Format(o.layout),
+			maxVal.Format(o.layout))
+	}
+	return minVal, maxVal, nil
+}
+
+// TimeBounds returns the range [MinVal, MaxVal) of values of a uniform datetime, date or time generator.
+func TimeBounds(args config.ColumnDef) (time.Time, time.Time, error) {
+	o, err := newTimeOutput(args)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return o.bounds(args)
+}
+
+// ParseTime parses First, MinVal or MaxVal of a datetime, date or time generator.
+func ParseTime(args config.ColumnDef, s string) (time.Time, error) {
+	o, err := newTimeOutput(args)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return o.parse(s)
 }
 
+// datetimeUniformGenerator generates values uniformly from a range counted in units: days for dates, seconds or
+// their fractions for datetimes and times.
 type datetimeUniformGenerator struct {
 	rng    *rand.Rand
-	dtFmt  string
-	minVal int64
-	spread int64
+	out    timeOutput
+	minVal time.Time
+	spread int64 // Number of units in the range.
+	sorted bool
+	left   int     // Rows left to generate when sorted.
+	pos    float64 // Position of the last sorted value in the range, from 0 to 1.
 }
 
 func NewDatetimeUniformGenerator(args config.ColumnDef) Generator {
-	g := datetimeUniformGenerator{
-		rng:   newRng(),
-		dtFmt: "2006-01-02 15:04:05",
-	}
-	minVal := time.Date(1970, 1, 0, 0, 0, 0, 0, time.UTC).Unix()
-	maxVal := time.Now().UTC().Unix()
-	minVal = parseDT(g.dtFmt, args.MinVal, minVal)
-	maxVal = parseDT(g.dtFmt, args.MaxVal, maxVal)
-	if minVal >= maxVal {
-		log.Panicf(
-			"minVal not smaller than maxVal: %s < %s",
-			time.Unix(minVal, 0).Format(g.dtFmt),
-			time.Unix(maxVal, 0).Format(g.dtFmt),
-		)
-	}
-	g.minVal = minVal
-	g.spread = maxVal - minVal
+	return newDatetimeUniformGenerator(args, defaultSource)
+}
+
+func newDatetimeUniformGenerator(args config.ColumnDef, src source) Generator {
+	out, err := newTimeOutput(args)
+	if err != nil {
+		log.Panic(err)
+	}
+	minVal, maxVal, err := out.bounds(args)
+	if err != nil {
+		log.Panic(err)
+	}
+	g := datetimeUniformGenerator{rng: src.rng(), out: out, minVal: minVal}
+	if out.kind.days {
+		y, m, d := maxVal.Date()
+		y0, m0, d0 := minVal.Date()
+		days := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Sub(time.Date(y0, m0, d0, 0, 0, 0, 0, time.UTC)).Hours() / 24
+		g.spread = int64(math.Round(days))
+	} else {
+		secs := maxVal.Unix() - minVal.Unix()
+		frac := int64(maxVal.Nanosecond()-minVal.Nanosecond()) * out.scale / int64(time.Second)
+		g.spread = secs*out.scale + frac
+	}
+	if g.spread <= 0 {
+		log.Panicf("the range from %s to %s is shorter than a single value", minVal, maxVal)
+	}
+	if args.Sorted {
+		if args.Rows <= 0 {
+			log.Panicf("sorted datetimes need the number of rows")
+		}
+		g.sorted, g.left = true, args.Rows
+	}
 	return &g
 }
 
-func (g *datetimeUniformGenerator) Next() interface{} {
-	secs := g.rng.Int63n(g.spread) + g.minVal
-	return time.Unix(secs, 0).UTC()
+func (g *datetimeUniformGenerator) Next() Value {
+	if g.sorted {
+		return g.out.value(g.at(g.nextSorted()))
+	}
+	return g.out.value(g.at(g.rng.Int63n(g.spread)))
+}
+
+// at returns the time the given number of units after minVal.
+func (g *datetimeUniformGenerator) at(offset int64) time.Time {
+	if g.out.kind.days {
+		return g.minVal.AddDate(0, 0, int(offset))
+	}
+	secs, frac := offset/g.out.scale, offset%g.out.scale
+	return time.Unix(g.minVal.Unix()+secs, int64(g.minVal.Nanosecond())+frac*(int64(time.Second)/g.out.scale))
+}
+
+// nextSorted returns the offset of the next of the sorted uniform values. The smallest of k uniform values from
+// [pos, 1) is 1-(1-pos)*U^(1/k), so the values are generated in order without storing them. Values past Rows stay at
+// the maximum.
+func (g *datetimeUniformGenerator) nextSorted() int64 {
+	if g.left > 0 {
+		g.pos = 1 - (1-g.pos)*math.Pow(g.rng.Float64(), 1/float64(g.left))
+		g.left--
+	}
+	offset := int64(g.pos * float64(g.spread))
+	if offset >= g.spread {
+		offset = g.spread - 1
+	}
+	return offset
+}
+
+// datetimeIncrementalGenerator adds a random step to the previous datetime, either uniform from [minStep, maxStep] or
+// exponential with the given mean, which makes the values arrivals of a Poisson process.
+type datetimeIncrementalGenerator struct {
+	rng      *rand.Rand
+	out      timeOutput
+	next     time.Time
+	minStep  int64 // Nanoseconds.
+	maxStep  int64
+	meanStep float64 // Nanoseconds, used instead of the range when positive.
+	partial  int64   // Nanoseconds of steps of dates that don't add up to a whole day yet.
 }
 
-func (g *datetimeUniformGenerator) DefaultFmtString() string {
-	return fmt.Sprintf("'%s'", g.dtFmt)
+func NewDatetimeIncrementalGenerator(args config.ColumnDef) Generator {
+	return newDatetimeIncrementalGenerator(args, defaultSource)
 }
 
-func parseDT(dtFmt, dt string, fallback int64) int64 {
-	if len(dt) == 0 {
+func newDatetimeIncrementalGenerator(args config.ColumnDef, src source) Generator {
+	out, err := newTimeOutput(args)
+	if err != nil {
+		log.Panic(err)
+	}
+	if args.First == "" {
+		args.First = "now"
+	}
+	first, err := out.parse(args.First)
+	if err != nil {
+		log.Panic(err)
+	}
+	g := datetimeIncrementalGenerator{rng: src.rng(), out: out, next: first.Truncate(time.Second / time.Duration(out.scale))}
+	if args.Rate != "" {
+		rate, err := config.ParseRate(args.Rate)
+		if err != nil {
+			log.Panic(err)
+		}
+		g.meanStep = float64(time.Second) / rate
+		return &g
+	}
+	minStep, maxStep := parseStep(args.MinVal, 0), parseStep(args.MaxVal, -1)
+	switch {
+	case maxStep < 0 && args.MinVal == "":
+		minStep, maxStep = int64(out.kind.maxStep), int64(out.kind.maxStep)
+	case maxStep < 0:
+		maxStep = minStep
+	}
+	if minStep < 0 || minStep > maxStep {
+		log.Panicf("invalid step range [%s, %s]", time.Duration(minStep), time.Duration(maxStep))
+	}
+	g.minStep, g.maxStep = minStep, maxStep
+	return &g
+}
+
+func (g *datetimeIncrementalGenerator) Next() Value {
+	v := g.next
+	var step int64
+	if g.meanStep > 0 {
+		step = int64(g.rng.ExpFloat64() * g.meanStep)
+	} else {
+		step = g.minStep + g.rng.Int63n(g.maxStep-g.minStep+1)
+	}
+	if g.out.kind.days {
+		// Whole days are added in the TimeZone, so that days of 23 or 25 hours don't repeat or skip dates.
+		step += g.partial
+		g.partial = step % int64(24*time.Hour)
+		g.next = g.next.AddDate(0, 0, int(step/int64(24*time.Hour)))
+		return g.out.value(v)
+	}
+	g.next = g.next.Add(time.Duration(step))
+	return g.out.value(v)
+}
+
+// parseStep parses a duration such as 1s, 5m or 1d into nanoseconds.
+func parseStep(s string, fallback int64) int64 {
+	if s == "" {
 		return fallback
 	}
-	v, err := time.Parse(dtFmt, dt)
+	d, err := ParseDuration(s)
 	if err != nil {
-		log.Panicf("error parsing datetime %q: %s", dt, err)
+		log.Panic(err)
+	}
+	return int64(d)
+}
+
+// ParseDuration parses durations like time.ParseDuration, but also accepts days (d) and weeks (w), e.g. -30d or
+// 1d12h.
+func ParseDuration(s string) (time.Duration, error) {
+	rest, neg := s, false
+	if rest != "" && (rest[0] == '-' || rest[0] == '+') {
+		rest, neg = rest[1:], rest[0] == '-'
+	}
+	if rest == "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var d time.Duration
+	for rest != "" {
+		i := strings.IndexFunc(rest, func(r rune) bool { return r != '.' && (r < '0' || r > '9') })
+		if i < 0 {
+			i = len(rest)
+		}
+		j := strings.IndexFunc(rest[i:], func(r rune) bool { return r == '.' || (r >= '0' && r <= '9') })
+		if j < 0 {
+			j = len(rest) - i
+		}
+		num, unit := rest[:i], rest[i:i+j]
+		rest = rest[i+j:]
+		var part time.Duration
+		var err error
+		switch unit {
+		case "d", "w":
+			var n float64
+			n, err = strconv.ParseFloat(num, 64)
+			part = time.Duration(n * float64(24*time.Hour))
+			if unit == "w" {
+				part *= 7
+			}
+		default:
+			part, err = time.ParseDuration(num + unit)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d += part
+	}
+	if neg {
+		d = -d
 	}
-	return v.Unix()
+	return d, nil
 }
@@ -2,7 +2,11 @@ package generators
 
 import (
 	"fmt"
+	"testing"
+	"time"
+
 	"github.com/bitstonks/syndi/internal/config"
+	"github.com/stretchr/testify/assert"
 )
 
 func ExampleNewDatetimeNowGenerator() {
@@ -14,6 +18,33 @@ func ExampleNewDatetimeNowGenerator() {
 	// Output: NOW()
 }
 
+func ExampleNewDatetimeNowGenerator_precision() {
+	args := config.ColumnDef{
+		Type:      "datetime/now",
+		Precision: 3, // Digits of fractional seconds, for DATETIME(3) columns
+	}
+	g, _ := GetGenerator(args)
+	fmt.Println(g.Next())
+	// Output: NOW(3)
+}
+
+func TestNowGeneratorOptions(t *testing.T) {
+	g, err := GetGenerator(config.ColumnDef{Type: "time/now", Precision: 6})
+	assert.NoError(t, err)
+	assert.Equal(t, "CURTIME(6)", g.Next().String())
+
+	for _, args := range []config.ColumnDef{
+		{Type: "date/now", Precision: 3},
+		{Type: "datetime/now", Precision: 7},
+		{Type: "datetime/now", TimeZone: "Europe/Ljubljana"},
+		{Type: "datetime/now", Epoch: "ms"},
+		{Type: "time/now", Layout: "15.04"},
+	} {
+		_, err := GetGenerator(args)
+		assert.Error(t, err, args)
+	}
+}
+
 func ExampleNewDatetimeUniformGenerator() {
 	args := config.ColumnDef{
 		Type:   "datetime/uniform",
@@ -24,3 +55,135 @@ func ExampleNewDatetimeUniformGenerator() {
 	fmt.Println(g.Next())
 	// Output: '2016-12-19 23:42:51'
 }
+
+func ExampleNewDatetimeUniformGenerator_sorted() {
+	args := config.ColumnDef{
+		Type:   "datetime/uniform",
+		MinVal: "2021-01-01 00:00:00",
+		MaxVal: "2021-01-02 00:00:00",
+		Sorted: true, // Non-decreasing over Rows values
+		Rows:   4,    // Set by the importer to TotalRecords
+	}
+	g, _ := GetGenerator(args)
+	for i := 0; i < 4; i++ {
+		fmt.Println(g.Next())
+	}
+	// Output:
+	// '2021-01-01 07:08:35'
+	// '2021-01-01 16:07:15'
+	// '2021-01-01 19:22:26'
+	// '2021-01-01 19:36:57'
+}
+
+func ExampleNewDatetimeIncrementalGenerator() {
+	args := config.ColumnDef{
+		Type:   "datetime/incremental",
+		First:  "2021-01-01 00:00:00", // Default is current time
+		MinVal: "1s",                  // Shortest step
+		MaxVal: "1m",                  // Longest step
+	}
+	g, _ := GetGenerator(args)
+	for i := 0; i < 3; i++ {
+		fmt.Println(g.Next())
+	}
+	// Output:
+	// '2021-01-01 00:00:00'
+	// '2021-01-01 00:00:50'
+	// '2021-01-01 00:01:45'
+}
+
+func ExampleNewDatetimeIncrementalGenerator_rate() {
+	args := config.ColumnDef{
+		Type:  "datetime/incremental",
+		First: "2021-01-01 00:00:00",
+		Rate:  "2/m", // Poisson arrivals, two per minute on average
+	}
+	g, _ := GetGenerator(args)
+	for i := 0; i < 3; i++ {
+		fmt.Println(g.Next())
+	}
+	// Output:
+	// '2021-01-01 00:00:00'
+	// '2021-01-01 00:00:06'
+	// '2021-01-01 00:00:13'
+}
+
+func ExampleNewDatetimeIncrementalGenerator_dates() {
+	args := config.ColumnDef{
+		Type:     "date/incremental",
+		First:    "2021-10-30",
+		MinVal:   "12h",              // Steps shorter than a day add up
+		TimeZone: "Europe/Ljubljana", // Days are counted in TimeZone, also the 25 hours long 2021-10-31
+	}
+	g, _ := GetGenerator(args)
+	for i := 0; i < 5; i++ {
+		fmt.Println(g.Next())
+	}
+	// Output:
+	// '2021-10-30'
+	// '2021-10-30'
+	// '2021-10-31'
+	// '2021-10-31'
+	// '2021-11-01'
+}
+
+func ExampleNewDatetimeUniformGenerator_options() {
+	args := config.ColumnDef{
+		Type:      "datetime/uniform",
+		Layout:    "02.01.2006 15:04", // Layout of MinVal and MaxVal
+		MinVal:    "15.08.2011 18:18", // In TimeZone
+		MaxVal:    "01.12.2021 21:54", // In TimeZone
+		TimeZone:  "America/New_York", // Default is UTC
+		Precision: 3,                  // Milliseconds
+	}
+	g, _ := GetGenerator(args)
+	fmt.Println(g.Next())
+	args.Epoch = "ms" // Unix epoch in s, ms or us instead of a datetime
+	g, _ = GetGenerator(args)
+	fmt.Println(g.Next())
+	// Output:
+	// '2015-04-02 09:08:55.074'
+	// 1427980135074
+}
+
+func ExampleNewDatetimeUniformGenerator_relative() {
+	timeNow = func() time.Time { return time.Date(2021, 12, 1, 12, 0, 0, 0, time.UTC) }
+	defer func() { timeNow = time.Now }()
+	args := config.ColumnDef{
+		Type:   "date/uniform",
+		MinVal: "-30d", // Relative to now, in days (d), weeks (w) or Go durations
+		MaxVal: "now",
+	}
+	g, _ := GetGenerator(args)
+	fmt.Println(g.Next())
+	// Output: '2021-11-25'
+}
+
+func ExampleNewDateNowGenerator() {
+	g, _ := GetGenerator(config.ColumnDef{Type: "date/now"})
+	fmt.Println(g.Next())
+	// Output: CURDATE()
+}
+
+func ExampleNewDatetimeUniformGenerator_time() {
+	args := config.ColumnDef{
+		Type:   "time/uniform",
+		MinVal: "08:00:00", // Default is 00:00:00
+		MaxVal: "16:00:00", // Default is the end of the day
+	}
+	g, _ := GetGenerator(args)
+	fmt.Println(g.Next())
+	// Output: '08:37:54'
+}
+
+func ExampleParseDuration() {
+	for _, s := range []string{"-30d", "1w", "1d12h", "+90m"} {
+		d, _ := ParseDuration(s)
+		fmt.Println(d)
+	}
+	// Output:
+	// -720h0m0s
+	// 168h0m0s
+	// 36h0m0s
+	// 1h30m0s
+}
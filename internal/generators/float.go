@@ -30,17 +30,20 @@ type floatUniformGenerator struct {
 }
 
 func NewFloatUniformGenerator(args config.ColumnDef) Generator {
+	return newFloatUniformGenerator(args, defaultSource)
+}
+
+func newFloatUniformGenerator(args config.ColumnDef, src source) Generator {
 	minVal, maxVal := parseMinMaxFloat(&args)
 	return &floatUniformGenerator{
-		rng:    newRng(),
+		rng:    src.rng(),
 		minVal: minVal,
 		spread: maxVal - minVal,
 	}
 }
 
-func (g *floatUniformGenerator) Next() interface{} {
-	v := g.minVal + g.rng.Float64()*g.spread
-	return v
+func (g *floatUniformGenerator) Next() Value {
+	return FloatValue(g.minVal + g.rng.Float64()*g.spread)
 }
 
 type floatNormalGenerator struct {
@@ -53,16 +56,20 @@ type floatNormalGenerator struct {
 // with the mean equal to the mean of args.MinVal and args.MaxVal
 // and with both MinVal and MaxVal one stDev away from the mean.
 func NewFloatNormalGenerator(args config.ColumnDef) Generator {
+	return newFloatNormalGenerator(args, defaultSource)
+}
+
+func newFloatNormalGenerator(args config.ColumnDef, src source) Generator {
 	minVal, maxVal := parseMinMaxFloat(&args)
 	return &floatNormalGenerator{
-		rng:   newRng(),
+		rng:   src.rng(),
 		mean:  (maxVal + minVal) / 2,
 		stDev: (maxVal - minVal) / 2,
 	}
 }
 
-func (g *floatNormalGenerator) Next() interface{} {
-	return g.rng.NormFloat64()*g.stDev + g.mean
+func (g *floatNormalGenerator) Next() Value {
+	return FloatValue(g.rng.NormFloat64()*g.stDev + g.mean)
 }
 
 type floatExpGenerator struct {
@@ -76,14 +83,18 @@ type floatExpGenerator struct {
 // (args.MinVal+args.MaxVal)/2. This means that around 15% of all
 // numbers generated will be bigger than args.MaxVal.
 func NewFloatExpGenerator(args config.ColumnDef) Generator {
+	return newFloatExpGenerator(args, defaultSource)
+}
+
+func newFloatExpGenerator(args config.ColumnDef, src source) Generator {
 	minVal, maxVal := parseMinMaxFloat(&args)
 	return &floatExpGenerator{
-		rng:    newRng(),
+		rng:    src.rng(),
 		minVal: minVal,
 		mean:   (maxVal - minVal) / 2,
 	}
 }
 
-func (g *floatExpGenerator) Next() interface{} {
-	return g.rng.ExpFloat64()*g.mean + g.minVal
+func (g *floatExpGenerator) Next() Value {
+	return FloatValue(g.rng.ExpFloat64()*g.mean + g.minVal)
 }
@@ -2,43 +2,33 @@ package generators
 
 import (
 	"fmt"
-	"time"
 )
 
-type DefaultFmt interface {
-	DefaultFmtString() string
-}
-
-type quotedFmt struct{}
-
-func (quotedFmt) DefaultFmtString() string {
-	return "'%v'"
-}
-
+// Formatter turns values into Raw SQL using a custom format: datetimes are formatted with time.Format layouts and
+// everything else with fmt.Sprintf. NULLs are kept.
 type Formatter struct {
 	fmtString string
 	generator Generator
 }
 
+// NewFormatter wraps the generator with a Formatter, unless fmtString is empty and the values are left as they are.
 func NewFormatter(g Generator, fmtString string) Generator {
 	if fmtString == "" {
-		if f, ok := g.(DefaultFmt); ok {
-			fmtString = f.DefaultFmtString()
-		} else {
-			fmtString = "%v"
-		}
+		return g
 	}
-
 	return &Formatter{
 		fmtString: fmtString,
 		generator: g,
 	}
 }
 
-func (f *Formatter) Next() interface{} {
+func (f *Formatter) Next() Value {
 	val := f.generator.Next()
-	if t, ok := val.(time.Time); ok {
-		return t.Format(f.fmtString)
+	switch val.Kind {
+	case Null:
+		return val
+	case Time:
+		return RawValue(val.Time.Format(f.fmtString))
 	}
-	return fmt.Sprintf(f.fmtString, val)
+	return RawValue(fmt.Sprintf(f.fmtString, val.Interface()))
 }
@@ -13,19 +13,19 @@ func TestFormatter(t *testing.T) {
 	})
 	t.Run("default value", func(t *testing.T) {
 		f := NewFormatter(g, "")
-		assert.Equal(t, "test", f.Next())
+		assert.Equal(t, "test", f.Next().String())
 	})
 	t.Run("simple", func(t *testing.T) {
 		f := NewFormatter(g, "%v")
-		assert.Equal(t, "test", f.Next())
+		assert.Equal(t, "test", f.Next().String())
 	})
 	t.Run("quoted", func(t *testing.T) {
 		f := NewFormatter(g, "'%v'")
-		assert.Equal(t, "'test'", f.Next())
+		assert.Equal(t, "'test'", f.Next().String())
 	})
 	t.Run("text", func(t *testing.T) {
 		f := NewFormatter(g, "The value is '%v'")
-		assert.Equal(t, "The value is 'test'", f.Next())
+		assert.Equal(t, "The value is 'test'", f.Next().String())
 	})
 }
 
@@ -34,7 +34,7 @@ func TestDatetimeDefaultFormatter(t *testing.T) {
 		MaxVal: "2006-01-02 15:04:05",
 	})
 	f := NewFormatter(g, "")
-	assert.Equal(t, "'1971-07-03 10:49:54'", f.Next())
+	assert.Equal(t, "'1971-07-03 10:49:54'", f.Next().String())
 }
 
 func TestDateUSFormatter(t *testing.T) {
@@ -42,5 +42,5 @@ func TestDateUSFormatter(t *testing.T) {
 		MaxVal: "2006-01-02 15:04:05",
 	})
 	f := NewFormatter(g, "Never forget 01/02/06")
-	assert.Equal(t, "Never forget 07/03/71", f.Next())
+	assert.Equal(t, "Never forget 07/03/71", f.Next().String())
 }
@@ -3,63 +3,151 @@ package generators
 import (
 	"fmt"
 	"math/rand"
+	"sync/atomic"
 	"time"
 
 	"github.com/bitstonks/syndi/internal/config"
+	"github.com/google/uuid"
 )
 
+// Generator produces values of a column.
 type Generator interface {
-	Next() interface{}
+	Next() Value
 }
 
-var generatorBuilders map[string]func(config.ColumnDef) Generator
+// builder creates a generator drawing its randomness from the source.
+type builder func(args config.ColumnDef, src source) Generator
 
-func RegisterGenerator(genType string, builder func(config.ColumnDef) Generator) {
-	generatorBuilders[genType] = builder
+var generatorBuilders map[string]builder
+
+// RegisterGenerator makes the generator available for the genType. Registered generators bring their own randomness.
+func RegisterGenerator(genType string, build func(config.ColumnDef) Generator) {
+	generatorBuilders[genType] = func(args config.ColumnDef, _ source) Generator {
+		return build(args)
+	}
+}
+
+func register(genType string, build builder) {
+	generatorBuilders[genType] = build
 }
 
 // GetGenerator will find a generator matching args.Type if one was registered or return an error.
 func GetGenerator(args config.ColumnDef) (Generator, error) {
+	return getGenerator(args, defaultSource)
+}
+
+// getGenerator is GetGenerator with the randomness taken from the source.
+func getGenerator(args config.ColumnDef, src source) (g Generator, err error) {
 	builder, ok := generatorBuilders[args.Type]
 	if !ok {
 		return nil, fmt.Errorf("generator of type %s doesn't exist", args.Type)
 	}
-	return MakeNullifier(NewFormatter(builder(args), args.Format), args.Nullable), nil
+	defer func() {
+		// Builders reject invalid configs with log.Panic, which panics with the message.
+		if r := recover(); r != nil {
+			msg, ok := r.(string)
+			if !ok {
+				panic(r)
+			}
+			g, err = nil, fmt.Errorf("invalid %s generator: %s", args.Type, msg)
+		}
+	}()
+	return makeNullifier(NewFormatter(builder(args, src), args.Format), args.Nullable, src), nil
 }
 
 func init() {
-	generatorBuilders = make(map[string]func(config.ColumnDef) Generator)
-	// Since the interface always uses strings we can use a unified (weighted) multiple choice random generator
-	// for all column types. We do give up on some validation by doing that.
-	// TODO: have specialized constructors that validate the data but still use OneOfGenerator behind the scenes
-	RegisterGenerator("oneof", NewOneOfGenerator)
-	RegisterGenerator("bool/oneof", NewOneOfGenerator)
-	RegisterGenerator("datetime/oneof", NewQuotedOneOfGenerator)
-	RegisterGenerator("float/oneof", NewOneOfGenerator)
-	RegisterGenerator("int/oneof", NewOneOfGenerator)
-	RegisterGenerator("string/oneof", NewQuotedOneOfGenerator)
-
-	RegisterGenerator("int/incremental-uniform", NewIntUniformIncrementalGenerator)
-
-	RegisterGenerator("bool", NewBoolGenerator)
-	RegisterGenerator("datetime", NewDatetimeNowGenerator)
-	RegisterGenerator("datetime/now", NewDatetimeNowGenerator)
-	RegisterGenerator("datetime/uniform", NewDatetimeUniformGenerator)
-	RegisterGenerator("float", NewFloatUniformGenerator)
-	RegisterGenerator("float/uniform", NewFloatUniformGenerator)
-	RegisterGenerator("float/normal", NewFloatNormalGenerator)
-	RegisterGenerator("float/exp", NewFloatExpGenerator)
-	RegisterGenerator("int", NewIntUniformGenerator)
-	RegisterGenerator("int/uniform", NewIntUniformGenerator)
-	RegisterGenerator("string", NewStringGenerator)
-	RegisterGenerator("string/rand", NewStringGenerator)
-	RegisterGenerator("string/text", NewTextGenerator)
-	RegisterGenerator("string/uuid", NewUuidGenerator)
+	generatorBuilders = make(map[string]builder)
+	// All column types share the same (weighted) multiple choice random generator, only the options are parsed into
+	// values of different kinds.
+	register("oneof", newOneOfGenerator)
+	register("bool/oneof", newIntOneOfGenerator)
+	register("datetime/oneof", newQuotedOneOfGenerator)
+	register("float/oneof", newFloatOneOfGenerator)
+	register("int/oneof", newIntOneOfGenerator)
+	register("string/oneof", newQuotedOneOfGenerator)
+
+	register("int/incremental-uniform", newIntUniformIncrementalGenerator)
+
+	register("bool", newBoolGenerator)
+	register("datetime", newDatetimeNowGenerator)
+	register("datetime/now", newDatetimeNowGenerator)
+	register("datetime/uniform", newDatetimeUniformGenerator)
+	register("datetime/incremental", newDatetimeIncrementalGenerator)
+	register("datetime/seasonal", newDatetimeSeasonalGenerator)
+	register("date/now", newDateNowGenerator)
+	register("date/uniform", newDatetimeUniformGenerator)
+	register("date/incremental", newDatetimeIncrementalGenerator)
+	register("date/seasonal", newDatetimeSeasonalGenerator)
+	register("time/now", newTimeNowGenerator)
+	register("time/uniform", newDatetimeUniformGenerator)
+	register("float", newFloatUniformGenerator)
+	register("float/uniform", newFloatUniformGenerator)
+	register("float/normal", newFloatNormalGenerator)
+	register("float/exp", newFloatExpGenerator)
+	register("float/histogram", newFloatHistogramGenerator)
+	register("float/randomwalk", newFloatRandomWalkGenerator)
+	register("int", newIntUniformGenerator)
+	register("int/uniform", newIntUniformGenerator)
+	register("int/histogram", newIntHistogramGenerator)
+	register("string", newStringGenerator)
+	register("string/rand", newStringGenerator)
+	register("string/text", newTextGenerator)
+	register("string/uuid", newUuidGenerator)
+}
+
+// source supplies the randomness of new generators. Generators of a column share the source, but usually get their
+// own *rand.Rand from it.
+type source interface {
+	rng() *rand.Rand      // Randomness of a new generator.
+	uuids() func() string // Uuids of a new generator.
 }
 
-// newRng is a proxy for random object generator, so we can monkey patch it in tests to make them deterministic
-var newRng = newRngFunc
+// defaultSource is used by GetGenerator and the exported constructors. It seeds generators with the time, unless
+// SetSeed replaced it.
+var defaultSource source = timeSource{}
+
+// timeSource seeds every generator with the current time and generates random uuids.
+type timeSource struct{}
 
-func newRngFunc() *rand.Rand {
+func (timeSource) rng() *rand.Rand {
 	return rand.New(rand.NewSource(time.Now().UnixNano()))
 }
+
+func (timeSource) uuids() func() string {
+	return uuid.NewString
+}
+
+// seededSource gives every generator its own source, seeded by a hash of seed and the order in which it was created.
+// Hashing keeps the streams of nearby seeds apart: with seed+n, the second generator of one seed would repeat the
+// first one of the next seed.
+type seededSource struct {
+	seed int64
+	n    int64 // Generators created so far, updated atomically.
+}
+
+func (s *seededSource) rng() *rand.Rand {
+	n := atomic.AddInt64(&s.n, 1)
+	return rand.New(rand.NewSource(int64(splitmix64(splitmix64(uint64(s.seed)) + uint64(n)))))
+}
+
+// splitmix64 is the finalizer of the SplitMix64 generator, which scrambles nearby inputs into unrelated outputs.
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// uuids reads its own source for every uuid generator, so that tables loaded concurrently don't share one.
+func (s *seededSource) uuids() func() string {
+	rng := s.rng()
+	return func() string {
+		return uuid.Must(uuid.NewRandomFromReader(rng)).String()
+	}
+}
+
+// SetSeed makes generators created after the call deterministic. Each generator gets its own source, seeded by seed
+// and the order in which it was created, so loading the same configs in the same order reproduces the same data.
+func SetSeed(seed int64) {
+	defaultSource = &seededSource{seed: seed}
+}
@@ -8,21 +8,28 @@ import (
 	"math/rand"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/bitstonks/syndi/internal/config"
 )
 
-func init() {
-	// Override default RNG generator to create a deterministic one for tests
-	newRng = func() *rand.Rand {
-		rng := rand.New(rand.NewSource(4))
-		return rng
-	}
-	uuidGen = func() string {
+// fixedSource makes the tests deterministic: every generator gets the same seed and all uuids are the same.
+type fixedSource struct{}
+
+func (fixedSource) rng() *rand.Rand {
+	return rand.New(rand.NewSource(4))
+}
+
+func (fixedSource) uuids() func() string {
+	return func() string {
 		return "4d618232-ae05-46d0-a270-2931ef3d9add"
 	}
 }
 
+func init() {
+	defaultSource = fixedSource{}
+}
+
 type readmeConfig map[string]config.ColumnDef
 
 func loadConfigFromReadme() (readmeConfig, error) {
@@ -34,13 +41,17 @@ func loadConfigFromReadme() (readmeConfig, error) {
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
-	isConfig := false
+	isSection, isConfig := false, false
 	for scanner.Scan() {
 		line := scanner.Text()
 		if isConfig && line == "```" {
 			break
 		}
-		if line == "```yaml" {
+		if line == "## Available data generators" {
+			isSection = true
+			continue
+		}
+		if isSection && line == "```yaml" {
 			isConfig = true
 			continue
 		}
@@ -63,28 +74,43 @@ func TestReadmeConfig(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	timeNow = func() time.Time { return time.Date(2021, 12, 1, 12, 0, 0, 0, time.UTC) }
+	defer func() { timeNow = time.Now }()
 	tests := map[string]string{
-		"bool1":     "1",
-		"bool2":     "1",
-		"datetime1": "NOW()",
-		"datetime2": "'2016-12-19 23:42:51'",
-		"datetime3": "'1970-01-01 00:00:00'",
-		"datetime4": "'We have a meeting on 12/19/16'",
-		"float1":    "2.577089127583518",
-		"float2":    "27.133352143941206",
-		"float3":    "12.181511239890659",
-		"float4":    "1.5",
-		"float5":    "{\"price\": 3.8}",
-		"int1":      "89",
-		"int2":      "1",
-		"int3":      "10",
-		"int4":      "'I will eat 7 donuts today.'",
-		"int5":      "'User #001'",
-		"string1":   "'bbbbyaayzcbzazx'",
-		"string2":   "'ibulum in. Fusce lacinia, mi vel viverra viverra, lacus velit vulputate justo, nec vehicula ipsum enim et ligula. Sed sed convallis ex. Nam lobortis a'",
-		"string3":   "'4d618232-ae05-46d0-a270-2931ef3d9add'",
-		"string4":   "'yes'",
-		"string5":   "NULL",
+		"bool1":      "1",
+		"bool2":      "1",
+		"date1":      "'2021-07-08'",
+		"date2":      "'2021-11-25'",
+		"datetime1":  "NOW(3)",
+		"datetime2":  "'2016-12-19 23:42:51'",
+		"datetime3":  "'1970-01-01 00:00:00'",
+		"datetime4":  "'We have a meeting on 12/19/16'",
+		"datetime5":  "'2021-01-01 00:00:00'",
+		"datetime6":  "'2021-01-01 00:00:00'",
+		"datetime7":  "'2011-08-21 01:46:40'",
+		"datetime8":  "'2015-04-02 09:08:55.074'",
+		"datetime9":  "1482190971000",
+		"datetime10": "'2021-01-19 16:53:20'",
+		"float1":     "2.577089127583518",
+		"float2":     "27.133352143941206",
+		"float3":     "12.181511239890659",
+		"float4":     "1.5",
+		"float5":     "{\"price\": 3.8}",
+		"float6":     "0.2552791923598719",
+		"float7":     "100.00",
+		"int1":       "89",
+		"int2":       "1",
+		"int3":       "10",
+		"int4":       "'I will eat 7 donuts today.'",
+		"int5":       "'User #001'",
+		"int6":       "67",
+		"string1":    "'bbbbyaayzcbzazx'",
+		"string2":    "'ibulum in. Fusce lacinia, mi vel viverra viverra, lacus velit vulputate justo, nec vehicula ipsum enim et ligula. Sed sed convallis ex. Nam lobortis a'",
+		"string3":    "'4d618232-ae05-46d0-a270-2931ef3d9add'",
+		"string4":    "'yes'",
+		"string5":    "NULL",
+		"string6":    "'rgl'",
+		"time1":      "'08:37:54'",
 	}
 	for col, _ := range c {
 		if _, ok := tests[col]; !ok {
@@ -96,10 +122,41 @@ func TestReadmeConfig(t *testing.T) {
 		if !ok {
 			t.Fatalf("config is missing column %s", col)
 		}
+		conf.Rows = 1000 // Set by the importer to TotalRecords.
 		g, err := GetGenerator(conf)
 		if err != nil {
 			t.Fatalf("unable to load generator for %s (Type: %s): %s", col, c[col].Type, err)
 		}
-		assert.Equal(t, expected, g.Next(), "column %s (Type: %s) is incorrect", col, c[col].Type)
+		assert.Equal(t, expected, g.Next().String(), "column %s (Type: %s) is incorrect", col, c[col].Type)
+	}
+}
+
+func TestSetSeed(t *testing.T) {
+	defer func(src source) {
+		defaultSource = src
+	}(defaultSource)
+	generate := func() []interface{} {
+		SetSeed(42)
+		var res []interface{}
+		for _, conf := range []config.ColumnDef{
+			{Type: "int", MinVal: "0", MaxVal: "1000000"},
+			{Type: "int", MinVal: "0", MaxVal: "1000000"},
+			{Type: "string/uuid"},
+			{Type: "string/uuid"},
+		} {
+			g, err := GetGenerator(conf)
+			assert.NoError(t, err)
+			res = append(res, g.Next(), g.Next())
+		}
+		return res
 	}
+	first := generate()
+	assert.Equal(t, first, generate())
+	// The second generator of a seed doesn't repeat the first one of the next seed.
+	SetSeed(43)
+	g, err := GetGenerator(config.ColumnDef{Type: "int", MinVal: "0", MaxVal: "1000000"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, first[2:4], []interface{}{g.Next(), g.Next()})
+	assert.NotEqual(t, first[0:2], first[2:4], "generators should not share the same source")
+	assert.NotEqual(t, first[4:6], first[6:8], "uuid generators should not share the same source")
 }
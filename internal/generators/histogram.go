@@ -0,0 +1,129 @@
+package generators
+
+import (
+	"log"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/bitstonks/syndi/internal/config"
+)
+
+// bucket is a range [lo, hi) of values.
+type bucket struct {
+	lo, hi float64
+}
+
+// histogram picks a bucket with weighted random and then a value from the bucket uniformly at random.
+// Buckets are written like OneOf options, where each option is either a range `lo..hi` or a single value.
+type histogram struct {
+	rng     *rand.Rand
+	buckets []bucket
+	weights []int
+	total   int
+}
+
+func newHistogram(opts string, rng *rand.Rand) *histogram {
+	weights, total := getMultipleChoice(opts)
+	h := &histogram{
+		rng:   rng,
+		total: total,
+	}
+	for _, w := range weights {
+		lo, hi := w.name, w.name
+		if i := strings.Index(w.name, ".."); i >= 0 {
+			lo, hi = w.name[:i], w.name[i+2:]
+		}
+		b := bucket{lo: parseBucketBound(lo), hi: parseBucketBound(hi)}
+		if b.hi < b.lo {
+			log.Panicf("bucket %q has the upper bound smaller than the lower one", w.name)
+		}
+		h.buckets = append(h.buckets, b)
+		h.weights = append(h.weights, w.weight)
+	}
+	return h
+}
+
+func parseBucketBound(raw string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil {
+		log.Panicf("Unable to parse bucket bound: %s", err)
+	}
+	return v
+}
+
+func (h *histogram) bucket() bucket {
+	n := h.rng.Intn(h.total)
+	for i, w := range h.weights {
+		n -= w
+		if n < 0 {
+			return h.buckets[i]
+		}
+	}
+	return h.buckets[len(h.buckets)-1]
+}
+
+func (h *histogram) nextFloat() float64 {
+	b := h.bucket()
+	return b.lo + h.rng.Float64()*(b.hi-b.lo)
+}
+
+// nextInt returns an integer from [lo, hi) of the chosen bucket or lo if the bucket is a single value.
+func (h *histogram) nextInt() int {
+	b := h.bucket()
+	lo, hi := int64(b.lo), int64(b.hi)
+	if hi <= lo {
+		return int(lo)
+	}
+	return int(lo + h.rng.Int63n(hi-lo))
+}
+
+// intRange returns the smallest and the largest value nextInt can return.
+func (h *histogram) intRange() (int, int) {
+	minVal, maxVal := math.Inf(1), math.Inf(-1)
+	for _, b := range h.buckets {
+		hi := b.hi - 1
+		if b.hi <= b.lo {
+			hi = b.lo
+		}
+		minVal, maxVal = math.Min(minVal, b.lo), math.Max(maxVal, hi)
+	}
+	return int(math.Max(minVal, math.MinInt32)), int(math.Min(maxVal, math.MaxInt32))
+}
+
+type intHistogramGenerator struct {
+	*histogram
+}
+
+// NewIntHistogramGenerator creates a generator of integers following an empirical distribution given as weighted
+// buckets in args.OneOf, e.g. `0..10:5;10..100:2;500` for 5 parts [0, 10), 2 parts [10, 100) and 1 part 500.
+func NewIntHistogramGenerator(args config.ColumnDef) Generator {
+	return newIntHistogramGenerator(args, defaultSource)
+}
+
+func newIntHistogramGenerator(args config.ColumnDef, src source) Generator {
+	return &intHistogramGenerator{newHistogram(args.OneOf, src.rng())}
+}
+
+func (g *intHistogramGenerator) Next() Value {
+	return IntValue(int64(g.nextInt()))
+}
+
+type floatHistogramGenerator struct {
+	*histogram
+}
+
+// NewFloatHistogramGenerator creates a generator of floats following an empirical distribution given as weighted
+// buckets in args.OneOf, e.g. `-1.5..0:1;0..2.5:4`.
+func NewFloatHistogramGenerator(args config.ColumnDef) Generator {
+	return newFloatHistogramGenerator(args, defaultSource)
+}
+
+func newFloatHistogramGenerator(args config.ColumnDef, src source) Generator {
+	return &floatHistogramGenerator{newHistogram(args.OneOf, src.rng())}
+}
+
+func (g *floatHistogramGenerator) Next() Value {
+	return FloatValue(g.nextFloat())
+}
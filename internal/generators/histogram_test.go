@@ -0,0 +1,27 @@
+package generators
+
+import (
+	"fmt"
+	"github.com/bitstonks/syndi/internal/config"
+)
+
+func ExampleNewIntHistogramGenerator() {
+	args := config.ColumnDef{
+		Type: "int/histogram",
+		// Numbers from [0, 10) are 5 times and 500 is 2 times more likely than numbers from [10, 100).
+		OneOf: "0..10:5;10..100:1;500:2",
+	}
+	g, _ := GetGenerator(args)
+	fmt.Println(g.Next())
+	// Output: 67
+}
+
+func ExampleNewFloatHistogramGenerator() {
+	args := config.ColumnDef{
+		Type:  "float/histogram",
+		OneOf: "-1.5..0:1;0..2.5:4", // Positive numbers are 4 times more likely.
+	}
+	g, _ := GetGenerator(args)
+	fmt.Println(g.Next())
+	// Output: 0.2552791923598719
+}
@@ -15,6 +15,10 @@ type intUniformGenerator struct {
 }
 
 func NewIntUniformGenerator(args config.ColumnDef) Generator {
+	return newIntUniformGenerator(args, defaultSource)
+}
+
+func newIntUniformGenerator(args config.ColumnDef, src source) Generator {
 	minVal, err := strconv.ParseInt(args.MinVal, 10, 64)
 	if err != nil {
 		log.Panicf("Unable to parse minVal: %s", err)
@@ -27,12 +31,12 @@ func NewIntUniformGenerator(args config.ColumnDef) Generator {
 		log.Panicf("minVal not smaller than maxVal: %d < %d", minVal, maxVal)
 	}
 	return &intUniformGenerator{
-		rng:    newRng(),
+		rng:    src.rng(),
 		minVal: int(minVal),
 		spread: int(maxVal - minVal),
 	}
 }
 
-func (g *intUniformGenerator) Next() interface{} {
-	return g.rng.Intn(g.spread) + g.minVal
+func (g *intUniformGenerator) Next() Value {
+	return IntValue(int64(g.rng.Intn(g.spread) + g.minVal))
 }
@@ -13,36 +13,21 @@ type intUniformIncrementalGenerator struct {
 }
 
 func NewIntUniformIncrementalGenerator(args config.ColumnDef) Generator {
+	return newIntUniformIncrementalGenerator(args, defaultSource)
+}
+
+func newIntUniformIncrementalGenerator(args config.ColumnDef, src source) Generator {
 	first, err := strconv.ParseInt(args.First, 10, 64)
 	if err != nil {
 		log.Panicf("Unable to parse first: %s", err)
 	}
 	return &intUniformIncrementalGenerator{
 		nextValue: first,
-		generator: NewIntUniformGenerator(args),
+		generator: newIntUniformGenerator(args, src),
 	}
 }
 
-func (g *intUniformIncrementalGenerator) Next() interface{} {
-	step := parseInt(g.generator.Next())
-	result := atomic.AddInt64(&g.nextValue, step) - step
-	return result
-}
-
-func parseInt(raw interface{}) int64 {
-	switch n := raw.(type) {
-	case int:
-		return int64(n)
-	case int64:
-		return n
-	case string:
-		parsed, err := strconv.ParseInt(n, 10, 64)
-		if err != nil {
-			log.Panicf("int generator returned invalid int: %s", err)
-		}
-		return parsed
-	default:
-		log.Panicf("unable to parse int from %v", n)
-		return 0
-	}
+func (g *intUniformIncrementalGenerator) Next() Value {
+	step := g.generator.Next().Int
+	return IntValue(atomic.AddInt64(&g.nextValue, step) - step)
 }
@@ -0,0 +1,93 @@
+package generators
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/google/uuid"
+)
+
+// Masker replaces real values with fake ones. The fake value is derived from a keyed hash of the real one, so the same
+// input always gets the same output, also in other tables masked with the same secret and column config. This keeps
+// joins on masked columns working. Only stateless generators give consistent results, e.g. incremental ones don't.
+type Masker struct {
+	gen    Generator
+	src    *hashSource
+	rng    *rand.Rand
+	secret []byte
+}
+
+// hashSource is a rand.Source producing SHA-256 blocks of its key and a counter. Keyed with the whole HMAC of a masked
+// value, every value gets its own stream of randomness instead of one of the 2^31 streams seeds of math/rand give,
+// which would make unique columns collide after some ten thousand rows.
+type hashSource struct {
+	key     []byte
+	counter uint64
+	block   [sha256.Size]byte
+	pos     int // Bytes of the block already used.
+}
+
+// Seed restarts the stream of the key at the given block.
+func (s *hashSource) Seed(seed int64) {
+	s.counter = uint64(seed)
+	s.pos = len(s.block)
+}
+
+func (s *hashSource) Uint64() uint64 {
+	if s.pos+8 > len(s.block) {
+		var counter [8]byte
+		binary.BigEndian.PutUint64(counter[:], s.counter)
+		h := sha256.New()
+		h.Write(s.key)
+		h.Write(counter[:])
+		h.Sum(s.block[:0])
+		s.counter++
+		s.pos = 0
+	}
+	v := binary.BigEndian.Uint64(s.block[s.pos:])
+	s.pos += 8
+	return v
+}
+
+func (s *hashSource) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// NewMasker creates a masker generating values like the generator configured by args would.
+func NewMasker(args config.ColumnDef, secret string) (*Masker, error) {
+	m := &Masker{src: &hashSource{}, secret: []byte(secret)}
+	m.rng = rand.New(m.src)
+	gen, err := getGenerator(args, maskSource{m.rng})
+	if err != nil {
+		return nil, err
+	}
+	m.gen = gen
+	return m, nil
+}
+
+// maskSource gives all generators of a masker the same randomness, which is reseeded for every masked value.
+type maskSource struct {
+	r *rand.Rand
+}
+
+func (s maskSource) rng() *rand.Rand {
+	return s.r
+}
+
+func (s maskSource) uuids() func() string {
+	return func() string {
+		return uuid.Must(uuid.NewRandomFromReader(s.r)).String()
+	}
+}
+
+// Mask returns the fake value for the real value.
+func (m *Masker) Mask(value string) Value {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(value))
+	m.src.key = mac.Sum(m.src.key[:0])
+	m.rng.Seed(0) // Also drops the bytes rand.Rand.Read buffered from the previous value.
+	return m.gen.Next()
+}
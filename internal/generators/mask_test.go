@@ -0,0 +1,54 @@
+package generators
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleMasker_Mask() {
+	args := config.ColumnDef{Type: "string/rand", Length: 8}
+	m, _ := NewMasker(args, "secret")
+	other, _ := NewMasker(args, "secret")
+	fmt.Println(m.Mask("alice@example.com") == other.Mask("alice@example.com"))
+	fmt.Println(m.Mask("alice@example.com") == m.Mask("bob@example.com"))
+	// Output:
+	// true
+	// false
+}
+
+func TestMaskerKeepsUniqueValuesDistinct(t *testing.T) {
+	m, err := NewMasker(config.ColumnDef{Type: "string/uuid"}, "secret")
+	assert.NoError(t, err)
+	const n = 100000
+	seen := make(map[string]bool, n)
+	for i := 0; i < n; i++ {
+		v := m.Mask(strconv.Itoa(i)).Str
+		assert.False(t, seen[v], "%s masked to the value of another uuid", strconv.Itoa(i))
+		seen[v] = true
+	}
+	assert.Len(t, seen, n)
+}
+
+func TestNewMaskerConcurrently(t *testing.T) {
+	// Maskers and other generators can be created at the same time, e.g. by tables imported concurrently.
+	args := config.ColumnDef{Type: "string/uuid"}
+	done := make(chan Value)
+	for i := 0; i < 4; i++ {
+		go func() {
+			m, err := NewMasker(args, "secret")
+			assert.NoError(t, err)
+			done <- m.Mask("alice")
+		}()
+	}
+	g, err := GetGenerator(args)
+	assert.NoError(t, err)
+	first := <-done
+	for i := 1; i < 4; i++ {
+		assert.Equal(t, first, <-done)
+	}
+	assert.NotEqual(t, first, g.Next())
+}
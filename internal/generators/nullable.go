@@ -11,19 +11,23 @@ type nullifier struct {
 }
 
 func MakeNullifier(gen Generator, nullable float64) Generator {
+	return makeNullifier(gen, nullable, defaultSource)
+}
+
+func makeNullifier(gen Generator, nullable float64, src source) Generator {
 	if nullable <= 0 {
 		return gen
 	}
 	return &nullifier{
-		rng:      newRng(),
+		rng:      src.rng(),
 		nullable: nullable,
 		gen:      gen,
 	}
 }
 
-func (n *nullifier) Next() interface{} {
+func (n *nullifier) Next() Value {
 	if n.rng.Float64() < n.nullable {
-		return "NULL"
+		return NullValue()
 	}
 	return n.gen.Next()
 }
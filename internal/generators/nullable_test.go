@@ -5,7 +5,7 @@ import (
 	"github.com/bitstonks/syndi/internal/config"
 )
 
-func ExampleMakeNullable_half() {
+func ExampleMakeNullifier_half() {
 	args := config.ColumnDef{
 		Type:     "oneof",
 		OneOf:    "ok", // only possible non-null value is 'ok'
@@ -19,7 +19,7 @@ func ExampleMakeNullable_half() {
 	// NULL
 }
 
-func ExampleMakeNullable_always() {
+func ExampleMakeNullifier_always() {
 	args := config.ColumnDef{
 		Type:     "int/uniform", // uniform random number
 		MinVal:   "0",           // from including 0
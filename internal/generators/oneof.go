@@ -14,40 +14,80 @@ import (
 type oneOfGenerator struct {
 	rng     *rand.Rand
 	weights []weighted
+	values  []Value // Parsed choices in the same order as weights.
 	total   int
 }
 
-// NewOneOfGenerator constructs a oneOfGenerator
-func NewOneOfGenerator(args config.ColumnDef) Generator {
+func newChoices(args config.ColumnDef, parse func(string) Value, src source) *oneOfGenerator {
 	weights, total := getMultipleChoice(args.OneOf)
-	return &oneOfGenerator{
-		rng:     newRng(),
+	g := &oneOfGenerator{
+		rng:     src.rng(),
 		weights: weights,
 		total:   total,
 	}
+	for _, w := range weights {
+		g.values = append(g.values, parse(w.name))
+	}
+	return g
 }
 
-func (g *oneOfGenerator) Next() interface{} {
-	n := g.rng.Intn(g.total)
-	for _, w := range g.weights {
-		n -= w.weight
-		if n < 0 {
-			return w.name
-		}
-	}
-	return ""
+// NewOneOfGenerator constructs a oneOfGenerator with choices written as SQL, e.g. numbers or expressions.
+func NewOneOfGenerator(args config.ColumnDef) Generator {
+	return newOneOfGenerator(args, defaultSource)
 }
 
-// quotedOneOfGenerator is a light wrapper for oneOfGenerator that wraps the generated strings in single quotes.
-type quotedOneOfGenerator struct {
-	*oneOfGenerator
-	quotedFmt
+func newOneOfGenerator(args config.ColumnDef, src source) Generator {
+	return newChoices(args, RawValue, src)
 }
 
+// NewQuotedOneOfGenerator constructs a oneOfGenerator with strings as choices.
 func NewQuotedOneOfGenerator(args config.ColumnDef) Generator {
-	return &quotedOneOfGenerator{
-		oneOfGenerator: NewOneOfGenerator(args).(*oneOfGenerator),
+	return newQuotedOneOfGenerator(args, defaultSource)
+}
+
+func newQuotedOneOfGenerator(args config.ColumnDef, src source) Generator {
+	return newChoices(args, StringValue, src)
+}
+
+// NewIntOneOfGenerator constructs a oneOfGenerator with integers as choices. Choices that aren't integers are kept as
+// SQL.
+func NewIntOneOfGenerator(args config.ColumnDef) Generator {
+	return newIntOneOfGenerator(args, defaultSource)
+}
+
+func newIntOneOfGenerator(args config.ColumnDef, src source) Generator {
+	return newChoices(args, func(s string) Value {
+		if v, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+			return IntValue(v)
+		}
+		return RawValue(s)
+	}, src)
+}
+
+// NewFloatOneOfGenerator constructs a oneOfGenerator with numbers as choices. Choices that aren't numbers are kept as
+// SQL.
+func NewFloatOneOfGenerator(args config.ColumnDef) Generator {
+	return newFloatOneOfGenerator(args, defaultSource)
+}
+
+func newFloatOneOfGenerator(args config.ColumnDef, src source) Generator {
+	return newChoices(args, func(s string) Value {
+		if v, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return FloatValue(v)
+		}
+		return RawValue(s)
+	}, src)
+}
+
+func (g *oneOfGenerator) Next() Value {
+	n := g.rng.Intn(g.total)
+	for i, w := range g.weights {
+		n -= w.weight
+		if n < 0 {
+			return g.values[i]
+		}
 	}
+	return NullValue()
 }
 
 type weighted struct {
@@ -0,0 +1,108 @@
+package generators
+
+import (
+	"log"
+	"math"
+	"math/rand"
+	"strconv"
+
+	"github.com/bitstonks/syndi/internal/config"
+)
+
+// defaultVolatility is used when Volatility isn't set, 1% per row.
+const defaultVolatility = 0.01
+
+// randomWalkGenerator is a geometric Brownian motion advancing with every row, like prices of a ticker. The logarithm
+// of the value changes by Drift - Volatility²/2 plus Volatility times a standard normal number, and is pulled back
+// towards the logarithm of First by Reversion. MinVal and MaxVal, if set, reflect the walk.
+type randomWalkGenerator struct {
+	rng       *rand.Rand
+	x         float64 // Logarithm of the current value.
+	mean      float64 // Logarithm of First, which the walk reverts to.
+	drift     float64 // Per row, already corrected by -Volatility²/2.
+	vol       float64
+	reversion float64
+	lo, hi    float64 // Logarithms of the floor and the ceiling.
+	floor     float64 // Zero if not set.
+	ceiling   float64 // Infinity if not set.
+	first     float64
+	started   bool // Whether first was returned.
+}
+
+func NewFloatRandomWalkGenerator(args config.ColumnDef) Generator {
+	return newFloatRandomWalkGenerator(args, defaultSource)
+}
+
+func newFloatRandomWalkGenerator(args config.ColumnDef, src source) Generator {
+	g := &randomWalkGenerator{
+		rng:       src.rng(),
+		vol:       defaultVolatility,
+		reversion: args.Reversion,
+		lo:        math.Inf(-1),
+		hi:        math.Inf(1),
+	}
+	if args.Volatility != nil {
+		g.vol = *args.Volatility // Zero makes the walk follow Drift only.
+	}
+	if g.vol < 0 || g.reversion < 0 || g.reversion > 1 {
+		log.Panicf("float/randomwalk needs a non-negative Volatility and Reversion between 0 and 1")
+	}
+	g.drift = args.Drift - g.vol*g.vol/2
+	minVal, maxVal := parseWalkBound(args.MinVal, "MinVal"), parseWalkBound(args.MaxVal, "MaxVal")
+	g.floor, g.ceiling = minVal, math.Inf(1)
+	if minVal > 0 {
+		g.lo = math.Log(minVal)
+	}
+	if maxVal > 0 {
+		g.hi, g.ceiling = math.Log(maxVal), maxVal
+	}
+	if g.lo >= g.hi {
+		log.Panicf("minVal not smaller than maxVal: %g < %g", minVal, maxVal)
+	}
+	switch {
+	case args.First != "":
+		g.first = parseWalkBound(args.First, "First")
+	case minVal > 0 && maxVal > 0:
+		g.first = math.Sqrt(minVal * maxVal)
+	default:
+		log.Panicf("float/randomwalk needs First, or MinVal and MaxVal to start in between")
+	}
+	g.x = math.Log(g.first)
+	if g.x < g.lo || g.x > g.hi {
+		log.Panicf("First %s is not between MinVal and MaxVal", args.First)
+	}
+	g.mean = g.x
+	return g
+}
+
+// parseWalkBound parses a positive number, or returns zero for an empty string.
+func parseWalkBound(raw, name string) float64 {
+	if raw == "" {
+		return 0
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		log.Panicf("Unable to parse %s: %s", name, err)
+	}
+	if v <= 0 {
+		log.Panicf("%s of float/randomwalk must be positive, got %g", name, v)
+	}
+	return v
+}
+
+func (g *randomWalkGenerator) Next() Value {
+	if !g.started {
+		g.started = true
+		return FloatValue(g.first)
+	}
+	g.x += g.reversion*(g.mean-g.x) + g.drift + g.vol*g.rng.NormFloat64()
+	if g.x > g.hi {
+		g.x = 2*g.hi - g.x
+	}
+	if g.x < g.lo {
+		g.x = 2*g.lo - g.x
+	}
+	g.x = math.Max(g.lo, math.Min(g.hi, g.x))
+	// Rounding errors of exp(log(x)) could cross the bounds.
+	return FloatValue(math.Max(g.floor, math.Min(g.ceiling, math.Exp(g.x))))
+}
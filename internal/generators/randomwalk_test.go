@@ -0,0 +1,58 @@
+package generators
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleNewFloatRandomWalkGenerator() {
+	vol := 0.02
+	args := config.ColumnDef{
+		Type:       "float/randomwalk",
+		First:      "100",
+		Volatility: &vol,
+		MinVal:     "95",
+		MaxVal:     "110",
+		Format:     "%.2f",
+	}
+	g, _ := GetGenerator(args)
+	// Returns First and then changes it by about 2% with every row, staying between 95 and 110.
+	for i := 0; i < 5; i++ {
+		fmt.Println(g.Next())
+	}
+	// Output:
+	// 100.00
+	// 101.42
+	// 101.90
+	// 105.11
+	// 104.75
+}
+
+func TestRandomWalkBounds(t *testing.T) {
+	vol := 0.1
+	g := NewFloatRandomWalkGenerator(config.ColumnDef{First: "100", Volatility: &vol, MinVal: "95", MaxVal: "110"})
+	for i := 0; i < 10000; i++ {
+		v := g.Next().Float
+		assert.True(t, v >= 95 && v <= 110, "%g out of bounds", v)
+	}
+	assert.Panics(t, func() { NewFloatRandomWalkGenerator(config.ColumnDef{}) })
+	assert.Panics(t, func() { NewFloatRandomWalkGenerator(config.ColumnDef{First: "-1"}) })
+	assert.Panics(t, func() { NewFloatRandomWalkGenerator(config.ColumnDef{First: "300", MaxVal: "200"}) })
+}
+
+func TestRandomWalkVolatility(t *testing.T) {
+	zero, negative := 0.0, -0.1
+	g := NewFloatRandomWalkGenerator(config.ColumnDef{First: "100", Drift: 0.01, Volatility: &zero})
+	prev := g.Next().Float
+	assert.Equal(t, 100.0, prev)
+	for i := 0; i < 10; i++ {
+		v := g.Next().Float
+		assert.InDelta(t, math.Exp(0.01), v/prev, 1e-9, "zero Volatility follows Drift only")
+		prev = v
+	}
+	assert.Panics(t, func() { NewFloatRandomWalkGenerator(config.ColumnDef{First: "100", Volatility: &negative}) })
+}
@@ -0,0 +1,186 @@
+package generators
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/bitstonks/syndi/internal/config"
+)
+
+// maxSeasonalTries limits the rejection sampling, so weights that leave (almost) no time in the range fall back to the
+// next time with a non-zero weight instead of looping forever. Patched in tests.
+var maxSeasonalTries = 1000000
+
+var (
+	weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"} // In the order of time.Weekday.
+	monthNames   = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+)
+
+// datetimeSeasonalGenerator generates datetimes from [MinVal, MaxVal) with the density given by weights of the hour of
+// day, the day of week and the month multiplied together. Candidates are drawn uniformly and accepted with the
+// probability of their weight relative to the largest one.
+type datetimeSeasonalGenerator struct {
+	uniform  *datetimeUniformGenerator
+	hours    [24]float64
+	weekdays [7]float64
+	months   [12]float64
+	max      float64
+}
+
+func NewDatetimeSeasonalGenerator(args config.ColumnDef) Generator {
+	return newDatetimeSeasonalGenerator(args, defaultSource)
+}
+
+func newDatetimeSeasonalGenerator(args config.ColumnDef, src source) Generator {
+	if args.Sorted {
+		log.Panic("seasonal datetimes can't be sorted")
+	}
+	g := datetimeSeasonalGenerator{uniform: newDatetimeUniformGenerator(args, src).(*datetimeUniformGenerator)}
+	hours := args.Hours
+	if g.uniform.out.kind.days {
+		hours = "" // Dates are all at midnight.
+	}
+	maxHour := parseSeasonWeights(g.hours[:], hours, nil)
+	maxWeekday := parseSeasonWeights(g.weekdays[:], args.Weekdays, weekdayNames)
+	maxMonth := parseSeasonWeights(g.months[:], args.Months, monthNames)
+	g.max = maxHour * maxWeekday * maxMonth
+	if g.max == 0 {
+		log.Panic("Hours, Weekdays and Months need at least one non-zero weight each")
+	}
+	g.max = g.rangeMax()
+	if g.max == 0 {
+		log.Panic("no hour, weekday and month with a non-zero weight falls between MinVal and MaxVal")
+	}
+	return &g
+}
+
+// step returns the number of units in an hour, or a day for dates. Weights don't change within a step.
+func (g *datetimeSeasonalGenerator) step() int64 {
+	if g.uniform.out.kind.days {
+		return 1
+	}
+	return 3600 * g.uniform.out.scale
+}
+
+func (g *datetimeSeasonalGenerator) weight(offset int64) float64 {
+	t := g.uniform.at(offset).In(g.uniform.out.loc)
+	return g.hours[t.Hour()] * g.weekdays[t.Weekday()] * g.months[t.Month()-1]
+}
+
+// rangeMax returns the largest weight of a time in the range, zero if there is none. A range of more than a year
+// holds every combination of hour, weekday and month, shorter ones are checked a step at a time.
+func (g *datetimeSeasonalGenerator) rangeMax() float64 {
+	u := g.uniform
+	year := int64(367)
+	if !u.out.kind.days {
+		year *= 24 * 3600 * u.out.scale
+	}
+	if u.spread >= year {
+		return g.max
+	}
+	maxWeight := 0.0
+	for offset := int64(0); ; offset += g.step() {
+		if offset >= u.spread {
+			offset = u.spread - 1 // The last hour may start after the last step.
+		}
+		maxWeight = math.Max(maxWeight, g.weight(offset))
+		if offset == u.spread-1 {
+			return maxWeight
+		}
+	}
+}
+
+// nextWeighted returns the first offset from the given one on with a non-zero weight, wrapping around at the end of
+// the range. The constructor made sure there is one, and there is one in every year of longer ranges.
+func (g *datetimeSeasonalGenerator) nextWeighted(offset int64) int64 {
+	u := g.uniform
+	for start := offset; ; offset += g.step() {
+		if offset >= u.spread {
+			offset = u.spread - 1
+		}
+		if g.weight(offset) > 0 {
+			return offset
+		}
+		if offset == u.spread-1 {
+			if start == 0 {
+				return start // Unreachable, rangeMax found a weighted time.
+			}
+			offset, start = -g.step(), 0
+		}
+	}
+}
+
+func (g *datetimeSeasonalGenerator) Next() Value {
+	u := g.uniform
+	for i := 0; i < maxSeasonalTries; i++ {
+		offset := u.rng.Int63n(u.spread)
+		if u.rng.Float64()*g.max < g.weight(offset) {
+			return u.out.value(u.at(offset))
+		}
+	}
+	return u.out.value(u.at(g.nextWeighted(u.rng.Int63n(u.spread))))
+}
+
+// parseSeasonWeights fills weights from options written like OneOf, e.g. `0..24:1;9..17:10`, and returns the largest
+// weight. Keys are numbers and ranges of numbers [lo, hi) if names are nil, or names and inclusive ranges of names
+// such as `mon..fri` otherwise. Later options override earlier ones, keys that aren't listed have zero weight and all
+// keys have the same weight if opts is empty.
+func parseSeasonWeights(weights []float64, opts string, names []string) float64 {
+	if opts == "" {
+		for i := range weights {
+			weights[i] = 1
+		}
+		return 1
+	}
+	choices, _ := getMultipleChoice(opts)
+	for _, c := range choices {
+		lo, hi := c.name, c.name
+		if i := strings.Index(c.name, ".."); i >= 0 {
+			lo, hi = c.name[:i], c.name[i+2:]
+		}
+		first, last := seasonKey(lo, names, len(weights)), seasonKey(hi, names, len(weights))
+		if names == nil {
+			if lo != hi {
+				last-- // Numeric ranges exclude the upper bound like histogram buckets.
+			}
+			if last < first || last >= len(weights) {
+				log.Panicf("invalid range %q", c.name)
+			}
+		}
+		// Ranges of names wrap around, e.g. `sat..sun` or `nov..feb`.
+		for k := first; ; k = (k + 1) % len(weights) {
+			weights[k] = float64(c.weight)
+			if k == last {
+				break
+			}
+		}
+	}
+	maxWeight := 0.0
+	for _, w := range weights {
+		if w > maxWeight {
+			maxWeight = w
+		}
+	}
+	return maxWeight
+}
+
+// seasonKey returns the index of a key: a number from [0, n] or one of the names.
+func seasonKey(key string, names []string, n int) int {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if names == nil {
+		k, err := strconv.Atoi(key)
+		if err != nil || k < 0 || k > n {
+			log.Panicf("%q is not a number from 0 to %d", key, n)
+		}
+		return k
+	}
+	for i, name := range names {
+		if strings.HasPrefix(key, name) {
+			return i
+		}
+	}
+	log.Panicf("%q is not one of: %s", key, strings.Join(names, ", "))
+	return 0
+}
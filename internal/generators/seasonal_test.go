@@ -0,0 +1,111 @@
+package generators
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleNewDatetimeSeasonalGenerator() {
+	args := config.ColumnDef{
+		Type:     "datetime/seasonal",
+		MinVal:   "2021-01-01 00:00:00",
+		MaxVal:   "2022-01-01 00:00:00",
+		Hours:    "0..24:1;9..17:10", // Business hours are 10 times more likely than nights
+		Weekdays: "mon..fri",         // No weekends
+		Months:   "jan..dec:1;dec:3", // December is 3 times busier than other months
+	}
+	g, _ := GetGenerator(args)
+	for i := 0; i < 3; i++ {
+		fmt.Println(g.Next())
+	}
+	// Output:
+	// '2021-01-19 16:53:20'
+	// '2021-12-27 11:13:03'
+	// '2021-02-12 12:47:04'
+}
+
+func TestSeasonalWeights(t *testing.T) {
+	g := NewDatetimeSeasonalGenerator(config.ColumnDef{
+		Type:     "datetime/seasonal",
+		MinVal:   "2021-01-01 00:00:00",
+		MaxVal:   "2022-01-01 00:00:00",
+		Hours:    "0..24:1;9..17:3",
+		Weekdays: "sat..sun",
+		Months:   "nov..feb",
+	})
+	business := 0
+	for i := 0; i < 10000; i++ {
+		v := g.Next().Time
+		assert.Contains(t, []time.Weekday{time.Saturday, time.Sunday}, v.Weekday())
+		assert.Contains(t, []time.Month{time.November, time.December, time.January, time.February}, v.Month())
+		if v.Hour() >= 9 && v.Hour() < 17 {
+			business++
+		}
+	}
+	// 8 of 24 hours with 3 times the weight take 8*3/(8*3+16) of the rows.
+	assert.InDelta(t, 0.6, float64(business)/10000, 0.03)
+
+	assert.Panics(t, func() { NewDatetimeSeasonalGenerator(config.ColumnDef{Type: "datetime/seasonal", Hours: "25"}) })
+	assert.Panics(t, func() {
+		NewDatetimeSeasonalGenerator(config.ColumnDef{Type: "datetime/seasonal", Weekdays: "someday"})
+	})
+	assert.Panics(t, func() {
+		NewDatetimeSeasonalGenerator(config.ColumnDef{Type: "datetime/seasonal", Sorted: true, Rows: 10})
+	})
+}
+
+func TestSeasonalWeightsInRange(t *testing.T) {
+	// A Saturday and Sunday in June.
+	weekend := config.ColumnDef{Type: "datetime/seasonal", MinVal: "2021-06-05 00:00:00", MaxVal: "2021-06-07 00:00:00"}
+	for _, c := range []struct {
+		hours, weekdays, months string
+		ok                      bool
+	}{
+		{"", "mon..fri", "", false},
+		{"", "sun", "", true},
+		{"", "", "jul..may", false},
+		{"23", "sun", "jun", true},
+		{"10..12", "sat", "", true},
+	} {
+		args := weekend
+		args.Hours, args.Weekdays, args.Months = c.hours, c.weekdays, c.months
+		if c.ok {
+			g := NewDatetimeSeasonalGenerator(args)
+			assert.Contains(t, []time.Weekday{time.Saturday, time.Sunday}, g.Next().Time.Weekday())
+		} else {
+			assert.Panics(t, func() { NewDatetimeSeasonalGenerator(args) }, c)
+		}
+	}
+
+	// The last hour starts after the last whole hour from MinVal.
+	args := config.ColumnDef{
+		Type: "datetime/seasonal", MinVal: "2021-06-05 10:30:00", MaxVal: "2021-06-05 12:15:00", Hours: "12",
+	}
+	assert.Equal(t, 12, NewDatetimeSeasonalGenerator(args).Next().Time.Hour())
+
+	dates := config.ColumnDef{Type: "date/seasonal", MinVal: "2021-06-05", MaxVal: "2021-06-07", Weekdays: "mon"}
+	assert.Panics(t, func() { NewDatetimeSeasonalGenerator(dates) })
+}
+
+func TestSeasonalFallback(t *testing.T) {
+	defer func(tries int) { maxSeasonalTries = tries }(maxSeasonalTries)
+	maxSeasonalTries = 0 // Always take the next time with a non-zero weight.
+
+	short := config.ColumnDef{
+		Type: "datetime/seasonal", MinVal: "2021-06-05 00:00:00", MaxVal: "2021-06-07 00:00:00", Hours: "23",
+		Weekdays: "sat",
+	}
+	long := config.ColumnDef{
+		Type: "datetime/seasonal", MinVal: "2020-03-01 00:00:00", MaxVal: "2022-03-01 00:00:00", Months: "feb",
+	}
+	gShort, gLong := NewDatetimeSeasonalGenerator(short), NewDatetimeSeasonalGenerator(long)
+	for i := 0; i < 100; i++ {
+		v := gShort.Next().Time
+		assert.Equal(t, time.Date(2021, 6, 5, 23, 0, 0, 0, time.UTC), v.Truncate(time.Hour))
+		assert.Equal(t, time.February, gLong.Next().Time.Month())
+	}
+}
@@ -1,35 +1,52 @@
 package generators
 
 import (
+	"log"
 	"math/rand"
 
 	"github.com/bitstonks/syndi/internal/config"
 )
 
 type stringGenerator struct {
-	rng *rand.Rand
-	len int
-	all []rune
-	quotedFmt
+	rng     *rand.Rand
+	len     int
+	lengths *histogram // Overrides len if set.
+	all     []rune
 }
 
 func NewStringGenerator(args config.ColumnDef) Generator {
+	return newStringGenerator(args, defaultSource)
+}
+
+func newStringGenerator(args config.ColumnDef, src source) Generator {
 	all := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
 	if len(args.OneOf) > 0 {
 		all = []rune(args.OneOf)
 	}
-	return &stringGenerator{
-		rng: newRng(),
+	g := &stringGenerator{
+		rng: src.rng(),
 		len: args.Length,
 		all: all,
 	}
+	minLen := args.Length
+	if len(args.Lengths) > 0 {
+		g.lengths = newHistogram(args.Lengths, src.rng())
+		minLen, _ = g.lengths.intRange()
+	}
+	if minLen < 0 {
+		log.Panicf("string lengths can't be negative, got %d", minLen)
+	}
+	return g
 }
 
-func (g *stringGenerator) Next() interface{} {
-	b := make([]rune, g.len)
+func (g *stringGenerator) Next() Value {
+	n := g.len
+	if g.lengths != nil {
+		n = g.lengths.nextInt()
+	}
+	b := make([]rune, n)
 	for i := range b {
 		b[i] = g.all[g.rng.Intn(len(g.all))]
 	}
-	return string(b)
-
+	return StringValue(string(b))
 }
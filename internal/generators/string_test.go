@@ -2,7 +2,10 @@ package generators
 
 import (
 	"fmt"
+	"testing"
+
 	"github.com/bitstonks/syndi/internal/config"
+	"github.com/stretchr/testify/assert"
 )
 
 func ExampleNewStringGenerator() {
@@ -25,3 +28,22 @@ func ExampleNewStringGenerator_charset() {
 	fmt.Println(g.Next())
 	// Output: 'dac b'
 }
+
+func ExampleNewStringGenerator_lengths() {
+	args := config.ColumnDef{
+		Type:    "string/rand",
+		Lengths: "3..6:3;10", // Lengths from [3, 6) are 3 times more likely than length 10.
+	}
+	g, _ := GetGenerator(args)
+	fmt.Println(g.Next())
+	// Output: 'rgl'
+}
+
+func TestStringInvalidLengths(t *testing.T) {
+	for _, lengths := range []string{"-5..10", "10..5", "3;-1"} {
+		_, err := GetGenerator(config.ColumnDef{Type: "string/rand", Lengths: lengths})
+		assert.Error(t, err, lengths)
+	}
+	_, err := GetGenerator(config.ColumnDef{Type: "string/rand", Length: -1})
+	assert.Error(t, err)
+}
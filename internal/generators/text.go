@@ -1,6 +1,7 @@
 package generators
 
 import (
+	"log"
 	"math/rand"
 	"strings"
 
@@ -32,21 +33,46 @@ Suspendisse ac dui lacus. Integer lectus nisi, congue ac mi quis, porta lobortis
 `, "\r\n", " ", -1)
 var lipsumLen = len(lipsum)
 
-// TODO: what if Len is actually greater than lipsumLen?
+// maxTextLength is the size of MEDIUMTEXT. Longer lengths are more likely typos than real data, and rows that long
+// don't fit in the default max_allowed_packet of MySQL in batches anyway.
+const maxTextLength = 1<<24 - 1
+
 type textGenerator struct {
-	rng *rand.Rand
-	len int
-	quotedFmt
+	rng     *rand.Rand
+	len     int
+	lengths *histogram // Overrides len if set.
+	corpus  string     // Lorem ipsum repeated to be longer than the longest length.
 }
 
 func NewTextGenerator(args config.ColumnDef) Generator {
-	return &textGenerator{
-		rng: newRng(),
-		len: args.Length,
+	return newTextGenerator(args, defaultSource)
+}
+
+func newTextGenerator(args config.ColumnDef, src source) Generator {
+	g := &textGenerator{
+		rng:    src.rng(),
+		len:    args.Length,
+		corpus: lipsum,
+	}
+	minLen, maxLen := args.Length, args.Length
+	if len(args.Lengths) > 0 {
+		g.lengths = newHistogram(args.Lengths, src.rng())
+		minLen, maxLen = g.lengths.intRange()
 	}
+	if minLen < 0 || maxLen > maxTextLength {
+		log.Panicf("text lengths must be between 0 and %d, got %d to %d", maxTextLength, minLen, maxLen)
+	}
+	if maxLen >= lipsumLen {
+		g.corpus = strings.Repeat(lipsum, maxLen/lipsumLen+1)
+	}
+	return g
 }
 
-func (g *textGenerator) Next() interface{} {
-	i := g.rng.Intn(lipsumLen - g.len)
-	return lipsum[i : i+g.len]
+func (g *textGenerator) Next() Value {
+	n := g.len
+	if g.lengths != nil {
+		n = g.lengths.nextInt()
+	}
+	i := g.rng.Intn(len(g.corpus) - n)
+	return StringValue(g.corpus[i : i+n])
 }
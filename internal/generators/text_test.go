@@ -2,7 +2,10 @@ package generators
 
 import (
 	"fmt"
+	"testing"
+
 	"github.com/bitstonks/syndi/internal/config"
+	"github.com/stretchr/testify/assert"
 )
 
 func ExampleNewTextGenerator() {
@@ -14,3 +17,31 @@ func ExampleNewTextGenerator() {
 	fmt.Println(g.Next())
 	// Output: 'esque lorem, sit amet malesuada quam consequat qui'
 }
+
+func ExampleNewTextGenerator_lengths() {
+	args := config.ColumnDef{
+		Type:    "string/text",
+		Lengths: "10..20:9;100", // Mostly short texts, but sometimes 100 characters long.
+	}
+	g, _ := GetGenerator(args)
+	fmt.Println(g.Next())
+	// Output: ' euismod mi dui vel velit. Mauris egestas dictum quam nec pellentesque. Vivamus quis fermentum augue'
+}
+
+func TestTextLongerThanCorpus(t *testing.T) {
+	g, err := GetGenerator(config.ColumnDef{Type: "string/text", Length: 3 * lipsumLen})
+	assert.NoError(t, err)
+	assert.Len(t, g.Next().Str, 3*lipsumLen)
+
+	g, err = GetGenerator(config.ColumnDef{Type: "string/text", Lengths: fmt.Sprintf("%d..%d", lipsumLen, 2*lipsumLen)})
+	assert.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		n := len(g.Next().Str)
+		assert.True(t, n >= lipsumLen && n < 2*lipsumLen, n)
+	}
+
+	for _, lengths := range []string{"-5..10", "10..1e12", "1e30"} {
+		_, err := GetGenerator(config.ColumnDef{Type: "string/text", Lengths: lengths})
+		assert.Error(t, err, lengths)
+	}
+}
@@ -2,21 +2,21 @@ package generators
 
 import (
 	"github.com/bitstonks/syndi/internal/config"
-	"github.com/google/uuid"
 )
 
-// It's here so that it can be monkey patched in tests
-var uuidGen = uuid.NewString
-
 type uuidGenerator struct {
-	quotedFmt
+	next func() string
 }
 
 // TODO: add length?
 func NewUuidGenerator(args config.ColumnDef) Generator {
-	return &uuidGenerator{}
+	return newUuidGenerator(args, defaultSource)
+}
+
+func newUuidGenerator(_ config.ColumnDef, src source) Generator {
+	return &uuidGenerator{next: src.uuids()}
 }
 
-func (g *uuidGenerator) Next() interface{} {
-	return uuidGen()
+func (g *uuidGenerator) Next() Value {
+	return StringValue(g.next())
 }
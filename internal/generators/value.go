@@ -0,0 +1,158 @@
+package generators
+
+import (
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Kind is the type of a generated value.
+type Kind uint8
+
+const (
+	Null   Kind = iota // SQL NULL.
+	Int                // Integer in Value.Int.
+	Float              // Floating point number in Value.Float.
+	String             // Text in Value.Str, quoted and escaped when written as SQL.
+	Time               // Datetime in Value.Time, written in the layout in Value.Str or DatetimeLayout if it's empty.
+	Raw                // SQL in Value.Str written as is, e.g. `NOW()` or the output of a custom Format.
+)
+
+// Layouts of Time values written as SQL literals.
+const (
+	DatetimeLayout = "2006-01-02 15:04:05" // The default.
+	DateLayout     = "2006-01-02"
+	TimeLayout     = "15:04:05"
+)
+
+// Value is a generated value. Generators return typed values and sinks decide how to write them, e.g. as SQL literals
+// or as JSON.
+type Value struct {
+	Kind  Kind
+	Int   int64
+	Float float64
+	Str   string
+	Time  time.Time
+}
+
+func NullValue() Value {
+	return Value{Kind: Null}
+}
+
+func IntValue(v int64) Value {
+	return Value{Kind: Int, Int: v}
+}
+
+func FloatValue(v float64) Value {
+	return Value{Kind: Float, Float: v}
+}
+
+func StringValue(v string) Value {
+	return Value{Kind: String, Str: v}
+}
+
+func TimeValue(v time.Time) Value {
+	return Value{Kind: Time, Time: v}
+}
+
+// TimeLayoutValue is a Time value written in the given layout instead of DatetimeLayout, e.g. DateLayout.
+func TimeLayoutValue(v time.Time, layout string) Value {
+	return Value{Kind: Time, Time: v, Str: layout}
+}
+
+func RawValue(sql string) Value {
+	return Value{Kind: Raw, Str: sql}
+}
+
+// Interface returns the value as a Go value: nil, int64, float64, string or time.Time.
+func (v Value) Interface() interface{} {
+	switch v.Kind {
+	case Int:
+		return v.Int
+	case Float:
+		return v.Float
+	case String, Raw:
+		return v.Str
+	case Time:
+		return v.Time
+	}
+	return nil
+}
+
+// Layout returns the layout a Time value is written in.
+func (v Value) Layout() string {
+	if v.Str == "" {
+		return DatetimeLayout
+	}
+	return v.Str
+}
+
+// String returns the value as an SQL literal.
+func (v Value) String() string {
+	return string(v.AppendSQL(nil))
+}
+
+// AppendSQL appends the value as an SQL literal to b and returns the extended buffer. It doesn't allocate when b has
+// enough capacity, which is what batch encoders use.
+func (v Value) AppendSQL(b []byte) []byte {
+	switch v.Kind {
+	case Null:
+		return append(b, "NULL"...)
+	case Int:
+		return strconv.AppendInt(b, v.Int, 10)
+	case Float:
+		return strconv.AppendFloat(b, v.Float, 'g', -1, 64)
+	case String:
+		return AppendQuoted(b, v.Str)
+	case Time:
+		b = append(b, '\'')
+		b = v.Time.AppendFormat(b, v.Layout())
+		return append(b, '\'')
+	}
+	return append(b, v.Str...)
+}
+
+// QuoteString formats the string as a MySQL string literal. Strings that aren't valid UTF-8 are written as hex
+// literals, so binary data survives.
+func QuoteString(s string) string {
+	return string(AppendQuoted(nil, s))
+}
+
+const hexDigits = "0123456789abcdef"
+
+// AppendQuoted appends the string as a MySQL string literal to b, see QuoteString.
+func AppendQuoted(b []byte, s string) []byte {
+	if !utf8.ValidString(s) {
+		b = append(b, "0x"...)
+		for i := 0; i < len(s); i++ {
+			b = append(b, hexDigits[s[i]>>4], hexDigits[s[i]&0x0f])
+		}
+		return b
+	}
+	b = append(b, '\'')
+	start := 0
+	for i := 0; i < len(s); i++ {
+		var esc byte
+		switch s[i] {
+		case '\\':
+			esc = '\\'
+		case '\'':
+			esc = '\''
+		case 0:
+			esc = '0'
+		case '\n':
+			esc = 'n'
+		case '\r':
+			esc = 'r'
+		case 0x1a:
+			esc = 'Z'
+		default:
+			continue
+		}
+		b = append(b, s[start:i]...)
+		b = append(b, '\\', esc)
+		start = i + 1
+	}
+	b = append(b, s[start:]...)
+	return append(b, '\'')
+}
@@ -0,0 +1,30 @@
+package generators
+
+import (
+	"fmt"
+	"time"
+)
+
+func ExampleValue_String() {
+	for _, v := range []Value{
+		NullValue(),
+		IntValue(-42),
+		FloatValue(0.25),
+		StringValue("it's\na test"),
+		StringValue("\xff\x00"),
+		TimeValue(time.Date(2021, 3, 1, 12, 30, 0, 0, time.UTC)),
+		TimeLayoutValue(time.Date(2021, 3, 1, 12, 30, 0, 0, time.UTC), DateLayout),
+		RawValue("NOW()"),
+	} {
+		fmt.Println(v)
+	}
+	// Output:
+	// NULL
+	// -42
+	// 0.25
+	// 'it\'s\na test'
+	// 0xff00
+	// '2021-03-01 12:30:00'
+	// '2021-03-01'
+	// NOW()
+}
@@ -1,63 +1,297 @@
 package importer
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
-	"sort"
 	"strings"
+	"time"
 
 	"github.com/bitstonks/syndi/internal/config"
 	"github.com/bitstonks/syndi/internal/generators"
+	"github.com/bitstonks/syndi/internal/manifest"
+	"github.com/bitstonks/syndi/internal/progress"
+	"github.com/bitstonks/syndi/internal/schema"
 )
 
+var errNoDB = errors.New("a database connection is needed")
+
 type Importer struct {
-	db   *sql.DB
+	db   *sql.DB   // Nil when writing to a sink other than a database.
+	conn *sql.Conn // Session of the writes and of the foreign key checks if set, see UseConn.
+	sink Sink
 	cfg  *config.TableDef
 	cols []string
 	gens []generators.Generator
+	prog *progress.Table // Nil unless progress is tracked, batches are logged instead.
+	keys *manifest.Table // Nil unless keys of inserted rows are recorded.
+	key  int             // Index of the recorded key column.
+}
+
+// pipelineDepth is how many generated batches can wait to be inserted while the current one is written.
+const pipelineDepth = 2
+
+// statsSink is implemented by sinks that count bytes written and retries.
+type statsSink interface {
+	Stats() SinkStats
+}
+
+// NewImporter creates an importer inserting generated rows into the database. It fails if a generator can't be created
+// from its column definition.
+func NewImporter(db *sql.DB, cfg *config.TableDef) (*Importer, error) {
+	sink := NewDBSink(db)
+	sink.MaxBytes = cfg.BatchBytes
+	sink.Conflict = cfg.OnConflict
+	if cfg.Mask != nil && cfg.SourceTable() == cfg.TableName {
+		// Masking in place overwrites the masked columns of the existing rows.
+		sink.Conflict = config.ConflictDef{Action: config.ConflictUpdate, Update: cfg.Columns.Names()}
+	}
+	im, err := NewSinkImporter(sink, cfg)
+	if err != nil {
+		return nil, err
+	}
+	im.db = db
+	return im, nil
 }
 
-func NewImporter(db *sql.DB, cfg *config.TableDef) *Importer {
-	im := Importer{db: db, cfg: cfg}
-	im.cols, im.gens = prepareColumnGenerators(cfg.Columns)
-	return &im
+// NewSinkImporter creates an importer writing generated rows to the sink. Without a database there are no foreign
+// keys to disable, while preflight checks and masking fail.
+func NewSinkImporter(sink Sink, cfg *config.TableDef) (*Importer, error) {
+	im := Importer{sink: sink, cfg: cfg}
+	var err error
+	im.cols, im.gens, err = prepareColumnGenerators(cfg.Columns, cfg.TotalRecords)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", cfg.TableName, err)
+	}
+	return &im, nil
 }
 
+// UseConn makes the importer write rows and toggle foreign key checks on the connection, so that they share the
+// session with each other and with the hooks run on it. Reading the schema and the rows to be masked uses the pool.
+func (im *Importer) UseConn(conn *sql.Conn) {
+	im.conn = conn
+	if s, ok := im.sink.(*DBSink); ok {
+		s.Conn = conn
+	}
+}
+
+// DisableFK disables foreign key checks for the session unless the import is safe. It only affects the inserts when
+// they run on the same connection, see UseConn.
 func (im *Importer) DisableFK() error {
-	if !im.cfg.SafeImport {
-		// TODO: does this even work?
+	if !im.cfg.SafeImport && im.db != nil {
 		log.Println("disabling FK checks")
-		_, err := im.db.Exec("SET FOREIGN_KEY_CHECKS=0")
+		_, err := im.exec("SET FOREIGN_KEY_CHECKS=0")
 		return err
 	}
 	return nil
 }
 
 func (im *Importer) EnableFK() error {
-	if !im.cfg.SafeImport {
+	if !im.cfg.SafeImport && im.db != nil {
 		log.Println("enabling FK checks")
-		_, err := im.db.Exec("SET FOREIGN_KEY_CHECKS=1")
+		_, err := im.exec("SET FOREIGN_KEY_CHECKS=1")
+		return err
+	}
+	return nil
+}
+
+// exec executes the statement on the connection of the importer, or on the pool if there is none.
+func (im *Importer) exec(query string) (sql.Result, error) {
+	if im.conn != nil {
+		res, err := im.conn.ExecContext(context.Background(), query)
+		return res, sessionError(err)
+	}
+	return im.db.Exec(query)
+}
+
+// Preflight checks the table definition against the schema of the table in the database, so that mismatches are
+// found before any data is inserted.
+func (im *Importer) Preflight() error {
+	if im.db == nil {
+		return errNoDB
+	}
+	t, err := schema.LoadTable(im.db, im.cfg.TableName)
+	if err != nil {
 		return err
 	}
+	if im.cfg.Mask != nil {
+		if _, _, err = im.maskSource(); err != nil {
+			return err
+		}
+	}
+	problems := schema.Check(im.cfg, t)
+	if len(problems) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(problems))
+	for _, p := range problems {
+		msgs = append(msgs, p.Error())
+	}
+	return fmt.Errorf("config for %s doesn't match the table:\n  %s", im.cfg.TableName, strings.Join(msgs, "\n  "))
+}
+
+// TrackProgress reports written batches to the progress table instead of logging them.
+func (im *Importer) TrackProgress(t *progress.Table) {
+	im.prog = t
+}
+
+// RecordKeys records keys of the inserted rows in the manifest, so that they can be deleted later. The key is the
+// single-column primary key of the table, which has to be generated. Tables that are masked or that can have conflicts
+// with existing rows aren't recorded, since their rows can't be told apart from the existing ones.
+func (im *Importer) RecordKeys(m *manifest.Manifest) error {
+	if im.cfg.Mask != nil {
+		return fmt.Errorf("%s is masked, not inserted", im.cfg.TableName)
+	}
+	if a := im.cfg.OnConflict.Action; a != "" && a != config.ConflictError {
+		return fmt.Errorf("%s can have conflicting rows with OnConflict %s", im.cfg.TableName, a)
+	}
+	if im.db == nil {
+		return errNoDB
+	}
+	t, err := schema.LoadTable(im.db, im.cfg.TableName)
+	if err != nil {
+		return err
+	}
+	key := primaryKey(t)
+	if key == "" {
+		return fmt.Errorf("%s has no single-column primary key", im.cfg.TableName)
+	}
+	im.key = im.cfg.Columns.Index(key)
+	if im.key < 0 {
+		return fmt.Errorf("primary key %s of %s isn't generated", key, im.cfg.TableName)
+	}
+	im.trackKeys(m.AddTable(im.cfg.TableName, key))
 	return nil
 }
 
+// trackKeys records keys of the written rows in the table of the manifest. A DBSink reports the rows of every executed
+// statement, so that rows of a batch failing in a later statement are recorded too.
+func (im *Importer) trackKeys(t *manifest.Table) {
+	im.keys = t
+	if s, ok := im.sink.(*DBSink); ok {
+		s.Written = im.addKeys
+	}
+}
+
+func (im *Importer) addKeys(rows [][]generators.Value) {
+	for _, row := range rows {
+		im.keys.Add(row[im.key])
+	}
+}
+
+// Import inserts TotalRecords generated rows into the table, or masks the source table if the config has a Mask.
 func (im *Importer) Import() error {
-	sqlPrefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", im.cfg.TableName, strings.Join(im.cols, ","))
+	return im.ImportUntil(nil)
+}
+
+// ImportUntil is Import that stops after the current batch once stop is closed.
+func (im *Importer) ImportUntil(stop <-chan struct{}) error {
+	if im.prog != nil {
+		im.prog.Begin()
+		defer im.prog.Done()
+	}
+	if im.cfg.Mask != nil {
+		return im.mask(stop)
+	}
+	return im.insert(stop)
+}
 
-	for rem := im.cfg.TotalRecords; rem > 0; rem -= im.cfg.BatchSize {
-		log.Printf("loading a batch of max %d out of remaining %d records", im.cfg.BatchSize, rem)
-		batch := generateBatch(min(rem, im.cfg.BatchSize), &im.gens)
-		query := sqlPrefix + strings.Join(batch, ",")
-		_, err := im.db.Exec(query)
+// stopped tells whether stop has been closed.
+func stopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// insert inserts TotalRecords generated rows. Batches are generated in another goroutine, so that generating the next
+// batch overlaps with writing the current one. The sink gets batches in order, one at a time.
+func (im *Importer) insert(stop <-chan struct{}) error {
+	table := im.cfg.TableName
+	free := make(chan [][]generators.Value, pipelineDepth+1) // Batches to be reused.
+	ready := make(chan [][]generators.Value, pipelineDepth)  // Batches to be written.
+	done := make(chan struct{})
+	defer close(done)
+	for i := 0; i < cap(free); i++ {
+		free <- nil
+	}
+	go func() {
+		defer close(ready)
+		for rem := im.cfg.TotalRecords; rem > 0; rem -= im.cfg.BatchSize {
+			var rows [][]generators.Value
+			select {
+			case rows = <-free:
+			case <-done:
+				return
+			}
+			rows = generateBatch(rows, min(rem, im.cfg.BatchSize), im.gens)
+			rowsGenerated.With(table).Add(float64(len(rows)))
+			select {
+			case ready <- rows:
+				queueDepth.With(table).Set(float64(len(ready)))
+			case <-done:
+				return
+			}
+		}
+	}()
+	rem := im.cfg.TotalRecords
+	for rows := range ready {
+		if stopped(stop) {
+			return nil
+		}
+		queueDepth.With(table).Set(float64(len(ready)))
+		if im.prog == nil {
+			log.Printf("loading a batch of %d out of remaining %d records", len(rows), rem)
+		}
+		err := im.writeBatch(im.cols, rows)
 		if err != nil {
 			return err
 		}
+		rem -= len(rows)
+		free <- rows
 	}
 	return nil
 }
 
+// writeBatch writes the rows of the columns to the sink and reports the progress.
+func (im *Importer) writeBatch(cols []string, rows [][]generators.Value) error {
+	before := im.sinkStats()
+	start := time.Now()
+	err := im.sink.WriteBatch(im.cfg.TableName, cols, rows)
+	latency := time.Since(start)
+	after := im.sinkStats()
+	batchDuration.With(im.cfg.TableName).Observe(latency.Seconds())
+	if _, ok := im.sink.(*DBSink); !ok && err == nil && im.keys != nil {
+		im.addKeys(rows)
+	}
+	if err == nil {
+		rowsInserted.With(im.cfg.TableName).Add(float64(len(rows)))
+		bytesWritten.With(im.cfg.TableName).Add(float64(after.Bytes - before.Bytes))
+		rowsIgnored.With(im.cfg.TableName).Add(float64(after.Ignored - before.Ignored))
+	}
+	if im.prog != nil {
+		for i := before.Retries; i < after.Retries; i++ {
+			im.prog.Retry()
+		}
+		im.prog.Result(after.Affected-before.Affected, after.Ignored-before.Ignored)
+		if err == nil {
+			im.prog.Batch(len(rows), int(after.Bytes-before.Bytes), latency)
+		}
+	}
+	return err
+}
+
+func (im *Importer) sinkStats() SinkStats {
+	if s, ok := im.sink.(statsSink); ok {
+		return s.Stats()
+	}
+	return SinkStats{}
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -65,36 +299,55 @@ func min(a, b int) int {
 	return b
 }
 
-func prepareColumnGenerators(columnsConfig map[string]config.ColumnDef) (cols []string, gens []generators.Generator) {
-	for col := range columnsConfig {
-		cols = append(cols, col)
-	}
-	sort.Strings(cols)
-
-	for _, col := range cols {
-		genArgs := columnsConfig[col]
-		if genArgs.Type == "" {
-			log.Panicf("no data type defined for column %q", col)
+// prepareColumnGenerators creates the generators of the columns for a table of the given number of rows.
+func prepareColumnGenerators(columnsConfig config.Columns, rows int) ([]string, []generators.Generator, error) {
+	var cols []string
+	var gens []generators.Generator
+	for _, col := range columnsConfig {
+		if col.Type == "" {
+			return nil, nil, fmt.Errorf("no data type defined for column %q", col.Name)
 		}
-		g, err := generators.GetGenerator(genArgs)
+		col.Rows = rows
+		g, err := generators.GetGenerator(col.ColumnDef)
 		if err != nil {
-			log.Panic(err)
+			return nil, nil, fmt.Errorf("column %s: %w", col.Name, err)
 		}
+		cols = append(cols, col.Name)
 		gens = append(gens, g)
 	}
-	return
+	return cols, gens, nil
 }
 
-func generateBatch(size int, gens *[]generators.Generator) []string {
-	res := make([]string, 0, size)
-	genSize := len(*gens)
+// generateBatch fills size rows with generated values, reusing rows (and their values) from the previous batch.
+func generateBatch(rows [][]generators.Value, size int, gens []generators.Generator) [][]generators.Value {
+	for len(rows) < size {
+		rows = append(rows, make([]generators.Value, len(gens)))
+	}
+	rows = rows[:size]
+	for _, row := range rows {
+		for j, g := range gens {
+			row[j] = g.Next()
+		}
+	}
+	return rows
+}
 
-	for i := 0; i < size; i++ {
-		single := make([]string, 0, genSize)
-		for j := 0; j < genSize; j++ {
-			single = append(single, fmt.Sprintf("%v", (*gens)[j].Next()))
+// Execer executes statements: a *sql.DB, or a *sql.Conn taken from it to keep the settings of the session.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// RunHooks executes the hook SQL statements one by one.
+func RunHooks(db Execer, stmts []string) error {
+	for _, stmt := range stmts {
+		log.Printf("running hook: %s", stmt)
+		_, err := db.ExecContext(context.Background(), stmt)
+		if err != nil {
+			if _, ok := db.(*sql.Conn); ok {
+				err = sessionError(err)
+			}
+			return fmt.Errorf("hook %q failed: %w", stmt, err)
 		}
-		res = append(res, "("+strings.Join(single, ",")+")")
 	}
-	return res
+	return nil
 }
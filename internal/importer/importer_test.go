@@ -0,0 +1,411 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/generators"
+	"github.com/bitstonks/syndi/internal/manifest"
+	"github.com/bitstonks/syndi/internal/metrics"
+	"github.com/bitstonks/syndi/internal/progress"
+	"github.com/bitstonks/syndi/internal/schema"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+var benchColumns = []config.ColumnDef{
+	{Type: "int/incremental-uniform", First: "1", MinVal: "1", MaxVal: "2"},
+	{Type: "int/uniform", MinVal: "0", MaxVal: "1000000"},
+	{Type: "float/uniform", MinVal: "0", MaxVal: "1000", Format: "%.2f"},
+	{Type: "string/rand", Length: 20},
+	{Type: "string/text", Length: 100, Nullable: 0.2},
+	{Type: "string/oneof", OneOf: "new:10;done:80;failed"},
+	{Type: "datetime/uniform", MinVal: "2020-01-01 00:00:00", MaxVal: "2021-01-01 00:00:00"},
+	{Type: "bool"},
+	{Type: "string/uuid"},
+	{Type: "int/histogram", OneOf: "0..10:5;10..100:2;500"},
+}
+
+// benchColumnDefs returns n columns cycling through benchColumns.
+func benchColumnDefs(n int) config.Columns {
+	cols := make(config.Columns, 0, n)
+	for i := 0; i < n; i++ {
+		cols = append(cols, config.Column{Name: fmt.Sprintf("c%d", i), ColumnDef: benchColumns[i%len(benchColumns)]})
+	}
+	return cols
+}
+
+// legacyBatch builds the statement the way it was built before values were typed: a string per value and row, joined
+// twice.
+func legacyBatch(table string, cols []string, size int, gens []generators.Generator) string {
+	res := make([]string, 0, size)
+	for i := 0; i < size; i++ {
+		single := make([]string, 0, len(gens))
+		for _, g := range gens {
+			single = append(single, fmt.Sprintf("%v", g.Next()))
+		}
+		res = append(res, "("+strings.Join(single, ",")+")")
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES ", table, strings.Join(cols, ",")) + strings.Join(res, ",")
+}
+
+func BenchmarkGenerateBatch(b *testing.B) {
+	const batchSize = 500
+	for _, width := range []int{3, 50} {
+		cols, gens, err := prepareColumnGenerators(benchColumnDefs(width), 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("columns=%d", width), func(b *testing.B) {
+			b.ReportAllocs()
+			var rows [][]generators.Value
+			var buf []byte
+			for i := 0; i < b.N; i++ {
+				rows = generateBatch(rows, batchSize, gens)
+				buf = appendInsert(buf[:0], "bench", cols, rows)
+			}
+		})
+		b.Run(fmt.Sprintf("columns=%d/legacy", width), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = legacyBatch("bench", cols, batchSize, gens)
+			}
+		})
+	}
+}
+
+func TestAppendInsert(t *testing.T) {
+	_, gens, err := prepareColumnGenerators(benchColumnDefs(len(benchColumns)), 0)
+	assert.NoError(t, err)
+	cols := []string{"a", "b"}
+	rows := generateBatch(nil, 2, gens[:2])
+	assert.Len(t, rows, 2)
+	rows = generateBatch(rows, 1, gens[:2])
+	assert.Len(t, rows, 1)
+
+	rows = [][]generators.Value{
+		{generators.IntValue(1), generators.StringValue("it's")},
+		{generators.NullValue(), generators.RawValue("NOW()")},
+	}
+	assert.Equal(t, "INSERT INTO `t` (`a`,`b`) VALUES (1,'it\\'s'),(NULL,NOW())",
+		string(appendInsert(nil, "t", cols, rows)))
+}
+
+func TestDBSinkSplitsBatches(t *testing.T) {
+	var stmts []string
+	sink := NewDBSink(nil)
+	sink.exec = func(query string) (int64, error) {
+		stmts = append(stmts, query)
+		return 0, nil
+	}
+	rows := [][]generators.Value{
+		{generators.IntValue(1), generators.StringValue("aaaa")},
+		{generators.IntValue(2), generators.StringValue("bb")},
+		{generators.IntValue(3), generators.StringValue("c")},
+	}
+	// The prefix takes 33 bytes and rows 10, 8 and 7.
+	sink.MaxBytes = 52
+	assert.NoError(t, sink.WriteBatch("t", []string{"a", "b"}, rows))
+	assert.Equal(t, []string{
+		"INSERT INTO `t` (`a`,`b`) VALUES (1,'aaaa'),(2,'bb')",
+		"INSERT INTO `t` (`a`,`b`) VALUES (3,'c')",
+	}, stmts)
+
+	stmts = nil
+	sink.MaxBytes = 1000
+	assert.NoError(t, sink.WriteBatch("t", []string{"a", "b"}, rows))
+	assert.Len(t, stmts, 1)
+
+	sink.MaxBytes = 36
+	assert.EqualError(t, sink.WriteBatch("t", []string{"a", "b"}, rows),
+		"a single row of t takes 10 bytes, which is more than the limit of 3")
+}
+
+func TestDBSinkQuotesNames(t *testing.T) {
+	var stmts []string
+	sink := NewDBSink(nil)
+	sink.MaxBytes = 1000
+	sink.exec = func(query string) (int64, error) {
+		stmts = append(stmts, query)
+		return 1, nil
+	}
+	rows := [][]generators.Value{{generators.IntValue(1), generators.StringValue("x"), generators.IntValue(2)}}
+	assert.NoError(t, sink.WriteBatch("order", []string{"key", "group", "user-id"}, rows))
+	assert.Equal(t, []string{"INSERT INTO `order` (`key`,`group`,`user-id`) VALUES (1,'x',2)"}, stmts)
+}
+
+func TestDBSinkRetries(t *testing.T) {
+	retryBackoff = time.Millisecond
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found"}
+	failures := 2
+	sink := NewDBSink(nil)
+	sink.MaxBytes = 1000
+	sink.exec = func(query string) (int64, error) {
+		if failures > 0 {
+			failures--
+			return 0, deadlock
+		}
+		return 0, nil
+	}
+	rows := [][]generators.Value{{generators.IntValue(1)}}
+	assert.NoError(t, sink.WriteBatch("t", []string{"a"}, rows))
+	assert.Equal(t, SinkStats{Bytes: 32, Retries: 2}, sink.Stats())
+
+	failures = DefaultRetries + 1
+	assert.Equal(t, deadlock, sink.WriteBatch("t", []string{"a"}, rows))
+
+	permanent := errors.New("syntax error")
+	sink.exec = func(query string) (int64, error) { return 0, permanent }
+	assert.Equal(t, permanent, sink.WriteBatch("t", []string{"a"}, rows))
+	assert.Equal(t, int64(2+DefaultRetries), sink.Stats().Retries)
+
+	// The insert may have been executed before the connection broke.
+	sink.exec = func(query string) (int64, error) { return 0, mysql.ErrInvalidConn }
+	assert.Equal(t, mysql.ErrInvalidConn, sink.WriteBatch("t", []string{"a"}, rows))
+	assert.Equal(t, int64(2+DefaultRetries), sink.Stats().Retries)
+}
+
+func TestDBSinkZeroValue(t *testing.T) {
+	// Nothing listens on the port, so the insert fails instead of panicking on the missing test hook.
+	db, err := sql.Open("mysql", "root@tcp(127.0.0.1:1)/test?timeout=100ms")
+	assert.NoError(t, err)
+	defer db.Close()
+	sink := &DBSink{DB: db, MaxBytes: 1000}
+	rows := [][]generators.Value{{generators.IntValue(1)}}
+	assert.Error(t, sink.WriteBatch("t", []string{"a"}, rows))
+}
+
+// flakyDriver opens connections that break after executing a single statement.
+type flakyDriver struct{}
+
+func (flakyDriver) Open(string) (driver.Conn, error) {
+	return &flakyConn{left: 1}, nil
+}
+
+type flakyConn struct {
+	left int
+}
+
+func (c *flakyConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *flakyConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *flakyConn) Close() error {
+	return nil
+}
+
+func (c *flakyConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	if c.left == 0 {
+		return nil, driver.ErrBadConn
+	}
+	c.left--
+	return driver.RowsAffected(1), nil
+}
+
+func init() {
+	sql.Register("flaky", flakyDriver{})
+}
+
+func TestDBSinkConnFailsWithoutSession(t *testing.T) {
+	db, err := sql.Open("flaky", "")
+	assert.NoError(t, err)
+	defer db.Close()
+	rows := [][]generators.Value{{generators.IntValue(1)}}
+
+	// The pool silently replaces broken connections, losing the settings of their sessions.
+	sink := &DBSink{DB: db, MaxBytes: 1000}
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, sink.WriteBatch("t", []string{"a"}, rows))
+	}
+
+	db, err = sql.Open("flaky", "") // Without used connections in the pool.
+	assert.NoError(t, err)
+	defer db.Close()
+	conn, err := db.Conn(context.Background())
+	assert.NoError(t, err)
+	defer conn.Close()
+	sink = &DBSink{DB: db, Conn: conn, MaxBytes: 1000}
+	assert.NoError(t, sink.WriteBatch("t", []string{"a"}, rows))
+	err = sink.WriteBatch("t", []string{"a"}, rows)
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+	assert.Contains(t, err.Error(), "lost the connection")
+	assert.ErrorIs(t, sink.WriteBatch("t", []string{"a"}, rows), sql.ErrConnDone)
+}
+
+func TestDBSinkOnConflict(t *testing.T) {
+	var stmts []string
+	sink := NewDBSink(nil)
+	sink.MaxBytes = 1000
+	sink.Conflict = config.ConflictDef{Action: config.ConflictIgnore}
+	sink.exec = func(query string) (int64, error) {
+		stmts = append(stmts, query)
+		return 1, nil
+	}
+	rows := [][]generators.Value{{generators.IntValue(1)}, {generators.IntValue(2)}, {generators.IntValue(3)}}
+	assert.NoError(t, sink.WriteBatch("t", []string{"a"}, rows))
+	assert.Equal(t, []string{"INSERT IGNORE INTO `t` (`a`) VALUES (1),(2),(3)"}, stmts)
+	assert.Equal(t, int64(1), sink.Stats().Affected)
+	assert.Equal(t, int64(2), sink.Stats().Ignored)
+
+	stmts = nil
+	sink.Conflict = config.ConflictDef{Action: config.ConflictUpdate}
+	sink.MaxBytes = 79
+	assert.NoError(t, sink.WriteBatch("t", []string{"a"}, rows))
+	assert.Equal(t, []string{
+		"INSERT INTO `t` (`a`) VALUES (1),(2) ON DUPLICATE KEY UPDATE `a`=VALUES(`a`)",
+		"INSERT INTO `t` (`a`) VALUES (3) ON DUPLICATE KEY UPDATE `a`=VALUES(`a`)",
+	}, stmts)
+	assert.Equal(t, int64(2), sink.Stats().Ignored, "only the ignore action ignores rows")
+}
+
+func TestNewSinkImporterRejectsInvalidColumns(t *testing.T) {
+	cfg := &config.TableDef{TableName: "t", TotalRecords: 1, BatchSize: 1, Columns: config.Columns{
+		{Name: "walk", ColumnDef: config.ColumnDef{Type: "float/randomwalk", First: "300", MaxVal: "200"}},
+	}}
+	_, err := NewSinkImporter(NewDBSink(nil), cfg)
+	assert.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "t: column walk: invalid float/randomwalk generator: "), err.Error())
+
+	cfg.Columns[0].Type = ""
+	_, err = NewSinkImporter(NewDBSink(nil), cfg)
+	assert.EqualError(t, err, `t: no data type defined for column "walk"`)
+}
+
+func TestImportTracksProgress(t *testing.T) {
+	sink := NewDBSink(nil)
+	sink.MaxBytes = 1000
+	sink.exec = func(query string) (int64, error) { return 0, nil }
+	cfg := &config.TableDef{TableName: "t", TotalRecords: 5, BatchSize: 2, Columns: config.Columns{
+		{Name: "a", ColumnDef: config.ColumnDef{Type: "int/uniform", MinVal: "1", MaxVal: "9"}},
+	}}
+	tracker, err := progress.NewTracker(&strings.Builder{}, progress.None, time.Hour)
+	assert.NoError(t, err)
+	prog := tracker.AddTable("t", cfg.TotalRecords)
+	im, err := NewSinkImporter(sink, cfg)
+	assert.NoError(t, err)
+	im.TrackProgress(prog)
+	assert.NoError(t, im.Import())
+	assert.Equal(t, int64(5), prog.Rows)
+	assert.Equal(t, int64(3), prog.Batches)
+	assert.Equal(t, sink.Stats().Bytes, prog.Bytes)
+	assert.False(t, prog.Ended.IsZero())
+
+	var out strings.Builder
+	assert.NoError(t, metrics.Default.Write(&out))
+	assert.Contains(t, out.String(), `syndi_rows_generated_total{table="t"} 5`)
+	assert.Contains(t, out.String(), `syndi_rows_inserted_total{table="t"} 5`)
+	assert.Contains(t, out.String(), `syndi_batch_duration_seconds_count{table="t"} 3`)
+}
+
+func TestImportRecordsKeys(t *testing.T) {
+	sink := NewDBSink(nil)
+	sink.MaxBytes = 1000
+	sink.exec = func(query string) (int64, error) { return 0, nil }
+	cfg := &config.TableDef{TableName: "keys", TotalRecords: 5, BatchSize: 2, Columns: config.Columns{
+		{Name: "name", ColumnDef: config.ColumnDef{Type: "string/oneof", OneOf: "a"}},
+		{Name: "id", ColumnDef: config.ColumnDef{Type: "int/incremental-uniform", First: "10", MinVal: "1", MaxVal: "2"}},
+	}}
+	im, err := NewSinkImporter(sink, cfg)
+	assert.NoError(t, err)
+	m := manifest.New("test", time.Now())
+	im.key = 1
+	im.trackKeys(m.AddTable("keys", "id"))
+	assert.NoError(t, im.Import())
+	assert.Equal(t, int64(5), m.Tables[0].Rows())
+	assert.Equal(t, int64(10), m.Tables[0].Ranges[0][0])
+
+	// Batches are split into statements of one row, the rows of the statement before the failing one stay inserted.
+	calls := 0
+	sink.MaxBytes = 51
+	sink.exec = func(query string) (int64, error) {
+		if calls++; calls == 2 {
+			return 0, errors.New("table is read only")
+		}
+		return 1, nil
+	}
+	m = manifest.New("test", time.Now())
+	im.trackKeys(m.AddTable("keys", "id"))
+	assert.Error(t, im.Import())
+	assert.Equal(t, [][2]int64{{15, 15}}, m.Tables[0].Ranges)
+
+	cfg.OnConflict.Action = config.ConflictIgnore
+	assert.EqualError(t, im.RecordKeys(m), "keys can have conflicting rows with OnConflict ignore")
+}
+
+func TestImportUntilStop(t *testing.T) {
+	stop := make(chan struct{})
+	calls := 0
+	sink := NewDBSink(nil)
+	sink.MaxBytes = 1000
+	sink.exec = func(query string) (int64, error) {
+		if calls++; calls == 1 {
+			close(stop)
+		}
+		return 1, nil
+	}
+	cfg := &config.TableDef{TableName: "t", TotalRecords: 10, BatchSize: 2, Columns: config.Columns{
+		{Name: "a", ColumnDef: config.ColumnDef{Type: "string/oneof", OneOf: "a"}},
+	}}
+	im, err := NewSinkImporter(sink, cfg)
+	assert.NoError(t, err)
+	assert.NoError(t, im.ImportUntil(stop))
+	assert.Equal(t, 1, calls)
+}
+
+func TestImportStopsOnError(t *testing.T) {
+	sink := NewDBSink(nil)
+	sink.MaxBytes = 1000
+	calls := 0
+	sink.exec = func(query string) (int64, error) {
+		calls++
+		return 0, errors.New("table is read only")
+	}
+	cfg := &config.TableDef{TableName: "stop", TotalRecords: 100, BatchSize: 1, Columns: config.Columns{
+		{Name: "a", ColumnDef: config.ColumnDef{Type: "int/uniform", MinVal: "1", MaxVal: "9"}},
+	}}
+	im, err := NewSinkImporter(sink, cfg)
+	assert.NoError(t, err)
+	assert.EqualError(t, im.Import(), "table is read only")
+	assert.Equal(t, 1, calls)
+}
+
+func TestErrorClass(t *testing.T) {
+	assert.Equal(t, "deadlock", errorClass(fmt.Errorf("insert: %w", &mysql.MySQLError{Number: 1213})))
+	assert.Equal(t, "duplicate_key", errorClass(&mysql.MySQLError{Number: 1062}))
+	assert.Equal(t, "server", errorClass(&mysql.MySQLError{Number: 1146}))
+	assert.Equal(t, "connection", errorClass(mysql.ErrInvalidConn))
+	assert.Equal(t, "other", errorClass(errors.New("boom")))
+}
+
+func TestMaskKey(t *testing.T) {
+	table := &schema.Table{Name: "t", Columns: []schema.Column{
+		{Name: "id", Key: "PRI"},
+		{Name: "email", Key: "UNI"},
+		{Name: "phone", Key: "UNI", Nullable: true},
+		{Name: "user_id", Key: "MUL"},
+	}}
+	key, err := maskKey(table, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "id", key)
+	key, err = maskKey(table, "email")
+	assert.NoError(t, err)
+	assert.Equal(t, "email", key)
+	// Pages following the last key would skip rows with the same key or loop over NULLs.
+	_, err = maskKey(table, "phone")
+	assert.EqualError(t, err, "key column phone of t has to be the primary key or unique and NOT NULL")
+	_, err = maskKey(table, "user_id")
+	assert.Error(t, err)
+	_, err = maskKey(table, "missing")
+	assert.EqualError(t, err, "key column missing doesn't exist in t")
+}
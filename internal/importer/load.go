@@ -0,0 +1,157 @@
+package importer
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/bitstonks/syndi/internal/generators"
+)
+
+// Load profiles change the rate over time.
+const (
+	ProfileFlat    = "flat"    // Constant rate.
+	ProfileDiurnal = "diurnal" // Peaks at 14:00 with 1.8x the rate and drops to 0.2x at 02:00 local time.
+	ProfileSpikes  = "spikes"  // 5x the rate for 10 seconds every 5 minutes.
+)
+
+// batchesPerSec is how many batches are inserted per second at most in load mode, batches are small to keep the
+// inserts evenly spread.
+const batchesPerSec = 10
+
+// LoadOptions control continuous load generation.
+type LoadOptions struct {
+	Rate     float64       // Rows per second.
+	Duration time.Duration // Zero means until stopped.
+	Profile  string        // How the rate changes over time, flat if empty.
+	Jitter   float64       // Random variation of the rate of each batch, e.g. 0.2 for ±20%.
+	// ExistingRows lets workloads update and delete rows that were in the table before the load, not just the ones it
+	// inserted.
+	ExistingRows bool
+}
+
+// Check validates the options.
+func (o LoadOptions) Check() error {
+	switch o.Profile {
+	case "", ProfileFlat, ProfileDiurnal, ProfileSpikes:
+	default:
+		return fmt.Errorf("unknown load profile %q, use one of: %s, %s, %s", o.Profile, ProfileFlat,
+			ProfileDiurnal, ProfileSpikes)
+	}
+	if o.Rate <= 0 {
+		return fmt.Errorf("rate must be positive")
+	}
+	if o.Jitter < 0 || o.Jitter >= 1 {
+		return fmt.Errorf("jitter must be at least 0 and less than 1")
+	}
+	return nil
+}
+
+// rateAt returns the rate at the time according to the profile, without jitter.
+func (o LoadOptions) rateAt(t time.Time) float64 {
+	switch o.Profile {
+	case ProfileDiurnal:
+		hour := float64(t.Hour()) + float64(t.Minute())/60 + float64(t.Second())/3600
+		return o.Rate * (1 + 0.8*math.Cos(2*math.Pi*(hour-14)/24))
+	case ProfileSpikes:
+		if t.Unix()%300 < 10 {
+			return o.Rate * 5
+		}
+	}
+	return o.Rate
+}
+
+// tokenBucket paces inserts: tokens (rows) are added at the current rate up to the capacity, and taking more than
+// there are means waiting for the missing ones.
+type tokenBucket struct {
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(capacity int, now time.Time) *tokenBucket {
+	return &tokenBucket{capacity: float64(capacity), tokens: float64(capacity), last: now}
+}
+
+// take takes n tokens and returns how long to wait until they have been added at the rate.
+func (b *tokenBucket) take(n int, rate float64, now time.Time) time.Duration {
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / rate * float64(time.Second))
+}
+
+// loadBatchSize returns a batch size giving about batchesPerSec batches per second at the rate.
+func loadBatchSize(rate float64, batchSize int) int {
+	n := int(rate / batchesPerSec)
+	if n < 1 {
+		n = 1
+	}
+	if batchSize > 0 && n > batchSize {
+		n = batchSize
+	}
+	return n
+}
+
+// Load keeps inserting generated rows at the rate of the options, until the duration passes or stop is closed. With a
+// Workload, some of the batches update or delete rows inserted before instead. Unlike Import, it ignores TotalRecords.
+func (im *Importer) Load(opts LoadOptions, stop <-chan struct{}) error {
+	if err := opts.Check(); err != nil {
+		return err
+	}
+	if im.prog != nil {
+		im.prog.Begin()
+		defer im.prog.Done()
+	}
+	var w *workload
+	if im.cfg.Workload != nil {
+		var err error
+		w, err = newWorkload(im.cfg.Workload, im.cfg.Columns)
+		if err != nil {
+			return fmt.Errorf("%s: %w", im.cfg.TableName, err)
+		}
+		if opts.ExistingRows {
+			if err = im.loadKeys(w); err != nil {
+				return err
+			}
+		}
+	}
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	size := loadBatchSize(opts.Rate, im.cfg.BatchSize)
+	start := time.Now()
+	bucket := newTokenBucket(size, start)
+	var rows [][]generators.Value
+	for batches := 1; ; batches++ {
+		now := time.Now()
+		rate := opts.rateAt(now)
+		if opts.Jitter > 0 {
+			rate *= 1 + opts.Jitter*(2*rng.Float64()-1)
+		}
+		wait := bucket.take(size, rate, now)
+		last := opts.Duration > 0 && now.Add(wait).Sub(start) >= opts.Duration
+		if last {
+			wait = opts.Duration - now.Sub(start)
+		}
+		select {
+		case <-time.After(wait):
+		case <-stop:
+			return nil
+		}
+		if last {
+			return nil
+		}
+		var err error
+		rows, err = im.step(w, rows, size)
+		if err != nil {
+			return err
+		}
+		if im.prog == nil && batches%(60*batchesPerSec) == 0 {
+			log.Printf("ran %d batches of %d records on %s", batches, size, im.cfg.TableName)
+		}
+	}
+}
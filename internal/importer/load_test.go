@@ -0,0 +1,98 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/generators"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newTokenBucket(10, start)
+	assert.Equal(t, time.Duration(0), b.take(10, 100, start))
+	assert.Equal(t, 100*time.Millisecond, b.take(10, 100, start))
+	// The previous take is paid for after 100ms, the next one after another 100ms.
+	assert.Equal(t, 100*time.Millisecond, b.take(10, 100, start.Add(100*time.Millisecond)))
+	// Tokens don't pile up above the capacity while idle.
+	assert.Equal(t, time.Duration(0), b.take(10, 100, start.Add(time.Hour)))
+	assert.Equal(t, 100*time.Millisecond, b.take(10, 100, start.Add(time.Hour)))
+}
+
+func TestRateAt(t *testing.T) {
+	day := func(h, m, s int) time.Time { return time.Date(2021, 1, 1, h, m, s, 0, time.Local) }
+	flat := LoadOptions{Rate: 100}
+	assert.Equal(t, 100.0, flat.rateAt(day(3, 0, 0)))
+	diurnal := LoadOptions{Rate: 100, Profile: ProfileDiurnal}
+	assert.InDelta(t, 180, diurnal.rateAt(day(14, 0, 0)), 1e-9)
+	assert.InDelta(t, 20, diurnal.rateAt(day(2, 0, 0)), 1e-9)
+	assert.InDelta(t, 100, diurnal.rateAt(day(8, 0, 0)), 1e-9)
+	spikes := LoadOptions{Rate: 100, Profile: ProfileSpikes}
+	assert.Equal(t, 500.0, spikes.rateAt(time.Unix(3000, 0)))
+	assert.Equal(t, 100.0, spikes.rateAt(time.Unix(3010, 0)))
+}
+
+func TestLoadBatchSize(t *testing.T) {
+	assert.Equal(t, 1, loadBatchSize(2, 1000))
+	assert.Equal(t, 50, loadBatchSize(500, 1000))
+	assert.Equal(t, 100, loadBatchSize(5000, 100))
+}
+
+func TestLoad(t *testing.T) {
+	sink := NewDBSink(nil)
+	sink.MaxBytes = 1000
+	var rows int
+	sink.exec = func(query string) (int64, error) { return 0, nil }
+	cfg := &config.TableDef{TableName: "load", TotalRecords: 1, BatchSize: 1000, Columns: config.Columns{
+		{Name: "a", ColumnDef: config.ColumnDef{Type: "int/uniform", MinVal: "1", MaxVal: "9"}},
+	}}
+	im, err := NewSinkImporter(&countingSink{sink, &rows}, cfg)
+	assert.NoError(t, err)
+
+	// Batches of 10 rows every 100ms, the first one right away.
+	assert.NoError(t, im.Load(LoadOptions{Rate: 100, Duration: 350 * time.Millisecond}, nil))
+	assert.Equal(t, 40, rows)
+
+	stop := make(chan struct{})
+	close(stop)
+	rows = 0
+	assert.NoError(t, im.Load(LoadOptions{Rate: 100}, stop))
+	assert.LessOrEqual(t, rows, 10)
+
+	assert.EqualError(t, im.Load(LoadOptions{Rate: 100, Profile: "weekly"}, nil),
+		`unknown load profile "weekly", use one of: flat, diurnal, spikes`)
+}
+
+func TestLoadTablesConcurrently(t *testing.T) {
+	// Seeded uuid generators of tables loaded at the same time must not share a source, which `go test -race` checks.
+	// Batches are small, so that the tables take turns many times.
+	generators.SetSeed(1)
+	done := make(chan error)
+	for _, name := range []string{"a", "b"} {
+		sink := NewDBSink(nil)
+		sink.MaxBytes = 100000
+		sink.exec = func(query string) (int64, error) { return 0, nil }
+		cfg := &config.TableDef{TableName: name, TotalRecords: 1, BatchSize: 100, Columns: config.Columns{
+			{Name: "id", ColumnDef: config.ColumnDef{Type: "string/uuid"}},
+		}}
+		im, err := NewSinkImporter(sink, cfg)
+		assert.NoError(t, err)
+		go func() {
+			done <- im.Load(LoadOptions{Rate: 100000, Duration: 100 * time.Millisecond}, nil)
+		}()
+	}
+	assert.NoError(t, <-done)
+	assert.NoError(t, <-done)
+}
+
+type countingSink struct {
+	Sink
+	rows *int
+}
+
+func (s *countingSink) WriteBatch(table string, columns []string, rows [][]generators.Value) error {
+	*s.rows += len(rows)
+	return s.Sink.WriteBatch(table, columns, rows)
+}
@@ -0,0 +1,187 @@
+package importer
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bitstonks/syndi/internal/generators"
+	"github.com/bitstonks/syndi/internal/schema"
+)
+
+// mask reads all rows of the source table in batches ordered by the key and writes them to the target table, replacing
+// values of the configured columns with masked ones. When the source is the target table itself, existing rows are
+// updated in place (see NewImporter).
+func (im *Importer) mask(stop <-chan struct{}) error {
+	if im.db == nil {
+		return errNoDB
+	}
+	source := im.cfg.SourceTable()
+	t, key, err := im.maskSource()
+	if err != nil {
+		return err
+	}
+
+	maskers := map[string]*generators.Masker{}
+	for _, col := range im.cfg.Columns {
+		if t.Column(col.Name) == nil {
+			return fmt.Errorf("column %s doesn't exist in %s", col.Name, source)
+		}
+		if source == im.cfg.TableName && col.Name == key {
+			return fmt.Errorf("key column %s can't be masked in place", key)
+		}
+		m, err := generators.NewMasker(col.ColumnDef, im.cfg.Mask.Secret)
+		if err != nil {
+			return err
+		}
+		maskers[col.Name] = m
+	}
+
+	cols := make([]string, 0, len(t.Columns))
+	quoted := make([]string, 0, len(t.Columns))
+	keyIdx := 0
+	for i, c := range t.Columns {
+		cols = append(cols, c.Name)
+		quoted = append(quoted, schema.QuoteIdent(c.Name))
+		if c.Name == key {
+			keyIdx = i
+		}
+	}
+	selectPrefix := fmt.Sprintf("SELECT %s FROM %s", strings.Join(quoted, ","), schema.QuoteIdent(source))
+	selectSuffix := fmt.Sprintf(" ORDER BY %s LIMIT %d", schema.QuoteIdent(key), im.cfg.BatchSize)
+
+	var last interface{}
+	for total := 0; !stopped(stop); {
+		query, args := selectPrefix+selectSuffix, []interface{}(nil)
+		if last != nil {
+			query = selectPrefix + fmt.Sprintf(" WHERE %s > ?", schema.QuoteIdent(key)) + selectSuffix
+			args = append(args, last)
+		}
+		batch, lastRow, err := im.maskBatch(t, maskers, query, args)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err = im.writeBatch(cols, batch); err != nil {
+			return err
+		}
+		total += len(batch)
+		if im.prog == nil {
+			log.Printf("masked %d records of %s", total, source)
+		}
+		last = lastRow[keyIdx]
+	}
+	return nil
+}
+
+// maskSource loads the source table and returns it together with the key to order its rows by.
+func (im *Importer) maskSource() (*schema.Table, string, error) {
+	t, err := schema.LoadTable(im.db, im.cfg.SourceTable())
+	if err != nil {
+		return nil, "", err
+	}
+	key, err := maskKey(t, im.cfg.Mask.Key)
+	if err != nil {
+		return nil, "", err
+	}
+	return t, key, nil
+}
+
+// maskBatch reads a batch of rows, masking the configured columns. It also returns the last row read.
+func (im *Importer) maskBatch(t *schema.Table, maskers map[string]*generators.Masker, query string,
+	args []interface{}) ([][]generators.Value, []interface{}, error) {
+	rows, err := im.db.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	var batch [][]generators.Value
+	values := make([]interface{}, len(t.Columns))
+	ptrs := make([]interface{}, len(t.Columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(ptrs...); err != nil {
+			return nil, nil, err
+		}
+		row := make([]generators.Value, 0, len(values))
+		for i, v := range values {
+			// NULLs stay NULLs, there's nothing to mask.
+			if m, ok := maskers[t.Columns[i].Name]; ok && v != nil {
+				row = append(row, m.Mask(rawValue(v)))
+			} else {
+				row = append(row, scannedValue(v))
+			}
+		}
+		batch = append(batch, row)
+	}
+	return batch, values, rows.Err()
+}
+
+// maskKey returns the key to order rows by, which is the single-column primary key unless configured otherwise. Rows
+// are read in pages following the last key read, so the key has to be unique and not NULL.
+func maskKey(t *schema.Table, key string) (string, error) {
+	if key != "" {
+		c := t.Column(key)
+		if c == nil {
+			return "", fmt.Errorf("key column %s doesn't exist in %s", key, t.Name)
+		}
+		if key != primaryKey(t) && (c.Key != "UNI" || c.Nullable) {
+			return "", fmt.Errorf("key column %s of %s has to be the primary key or unique and NOT NULL", key, t.Name)
+		}
+		return key, nil
+	}
+	pk := primaryKey(t)
+	if pk == "" {
+		return "", fmt.Errorf("%s needs a single-column primary key or Mask.Key to be masked", t.Name)
+	}
+	return pk, nil
+}
+
+// primaryKey returns the single-column primary key, or an empty string if the table has none or a composite one.
+func primaryKey(t *schema.Table) string {
+	var pk []string
+	for _, c := range t.Columns {
+		if c.Key == "PRI" {
+			pk = append(pk, c.Name)
+		}
+	}
+	if len(pk) != 1 {
+		return ""
+	}
+	return pk[0]
+}
+
+// scannedValue converts a scanned value to a generated one, so it can be written like one.
+func scannedValue(v interface{}) generators.Value {
+	switch val := v.(type) {
+	case nil:
+		return generators.NullValue()
+	case int64:
+		return generators.IntValue(val)
+	case float64:
+		return generators.FloatValue(val)
+	case []byte:
+		return generators.StringValue(string(val))
+	case time.Time:
+		return generators.RawValue("'" + val.Format("2006-01-02 15:04:05.999999") + "'")
+	default:
+		return generators.StringValue(fmt.Sprint(val))
+	}
+}
+
+// rawValue formats a scanned value as text.
+func rawValue(v interface{}) string {
+	switch val := v.(type) {
+	case []byte:
+		return string(val)
+	case time.Time:
+		return val.Format("2006-01-02 15:04:05.999999")
+	default:
+		return fmt.Sprint(val)
+	}
+}
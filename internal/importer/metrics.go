@@ -0,0 +1,52 @@
+package importer
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/bitstonks/syndi/internal/metrics"
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	rowsGenerated = metrics.Default.NewCounterVec("syndi_rows_generated_total",
+		"Rows generated, including those still waiting to be inserted.", "table")
+	rowsInserted = metrics.Default.NewCounterVec("syndi_rows_inserted_total",
+		"Rows inserted (or masked).", "table")
+	rowsIgnored = metrics.Default.NewCounterVec("syndi_rows_ignored_total",
+		"Inserted rows skipped because of conflicts with existing ones.", "table")
+	bytesWritten = metrics.Default.NewCounterVec("syndi_bytes_written_total",
+		"Size of the executed INSERT statements.", "table")
+	batchDuration = metrics.Default.NewHistogramVec("syndi_batch_duration_seconds",
+		"Time to write a batch, including retries.", metrics.DefBuckets, "table")
+	errorsTotal = metrics.Default.NewCounterVec("syndi_errors_total",
+		"Failed statements by class of the error, retried ones included.", "table", "class")
+	workloadRows = metrics.Default.NewCounterVec("syndi_workload_rows_total",
+		"Rows updated or deleted by workloads.", "table", "operation")
+	queueDepth = metrics.Default.NewGaugeVec("syndi_queue_depth",
+		"Generated batches waiting to be inserted.", "table")
+)
+
+// errorClass groups errors for the metrics.
+func errorClass(err error) string {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		switch myErr.Number {
+		case 1205:
+			return "lock_timeout"
+		case 1213:
+			return "deadlock"
+		case 1062:
+			return "duplicate_key"
+		case 1153:
+			return "packet_too_large"
+		}
+		return "server"
+	}
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) || errors.As(err, &netErr) {
+		return "connection"
+	}
+	return "other"
+}
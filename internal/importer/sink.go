@@ -0,0 +1,242 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/generators"
+	"github.com/bitstonks/syndi/internal/schema"
+)
+
+// packetMargin is left free in max_allowed_packet for the protocol overhead.
+const packetMargin = 1024
+
+// DefaultRetries is how many times a statement is retried after a transient error such as a deadlock.
+const DefaultRetries = 3
+
+// retryBackoff is the wait before the first retry, doubled for every next one.
+var retryBackoff = 100 * time.Millisecond
+
+// Sink receives batches of generated rows. The rows are reused for the next batch, so they must not be kept after
+// WriteBatch returns.
+type Sink interface {
+	WriteBatch(table string, columns []string, rows [][]generators.Value) error
+}
+
+// DBSink inserts batches into a database with multi-row INSERT statements. Batches that don't fit into MaxBytes are
+// split into several statements. A DBSink literal with DB set works too, but doesn't retry unless MaxRetries is set.
+type DBSink struct {
+	DB         *sql.DB
+	Conn       *sql.Conn          // Used instead of DB if set, so that the statements keep the settings of one session.
+	MaxBytes   int                // Maximum size of a statement, read from @@max_allowed_packet of the server if not set.
+	MaxRetries int                // Retries of a statement after a transient error.
+	Conflict   config.ConflictDef // How rows conflicting with existing ones are handled.
+	// Written is called with the rows of every executed statement if set. A batch split into several statements can fail
+	// after some of them have been executed already.
+	Written func(rows [][]generators.Value)
+
+	exec  func(query string) (int64, error) // Replaces DB in tests, returns the number of affected rows.
+	buf   []byte                            // Reused for the statements.
+	row   []byte                            // Reused for single rows.
+	stats SinkStats
+}
+
+// SinkStats counts what a sink has written so far.
+type SinkStats struct {
+	Bytes    int64 // Size of the executed statements.
+	Retries  int64 // Statements retried after transient errors.
+	Affected int64 // Rows affected as reported by the database, updated rows count twice in MySQL.
+	Ignored  int64 // Rows skipped because of conflicts with the ignore action.
+}
+
+// Stats returns the counts of everything written so far.
+func (s *DBSink) Stats() SinkStats {
+	return s.stats
+}
+
+// NewDBSink creates a sink inserting into the database.
+func NewDBSink(db *sql.DB) *DBSink {
+	return &DBSink{DB: db, MaxRetries: DefaultRetries}
+}
+
+// execQuery executes the query and returns the number of affected rows.
+func (s *DBSink) execQuery(query string) (int64, error) {
+	if s.exec != nil {
+		return s.exec(query)
+	}
+	var res sql.Result
+	var err error
+	if s.Conn != nil {
+		res, err = s.Conn.ExecContext(context.Background(), query)
+		err = sessionError(err)
+	} else {
+		res, err = s.DB.Exec(query)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// sessionError explains errors of a lost connection taken from the pool for its session. Unlike the pool, the
+// connection isn't replaced by a new one, which would silently run the rest of the statements without the settings
+// of the session, such as disabled foreign key checks.
+func sessionError(err error) error {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, sql.ErrConnDone) {
+		return fmt.Errorf("lost the connection and the settings of its session: %w", err)
+	}
+	return err
+}
+
+func (s *DBSink) WriteBatch(table string, columns []string, rows [][]generators.Value) error {
+	limit, err := s.maxBytes()
+	if err != nil {
+		return err
+	}
+	verb, suffix, err := schema.MySQL.InsertClauses(s.Conflict, columns)
+	if err != nil {
+		return fmt.Errorf("%s: %w", table, err)
+	}
+	s.buf = appendInsertPrefix(s.buf[:0], verb, table, columns)
+	prefixLen, n := len(s.buf), 0
+	limit -= len(suffix)
+	for i, row := range rows {
+		s.row = appendRow(s.row[:0], row)
+		if prefixLen+len(s.row) > limit {
+			return fmt.Errorf("a single row of %s takes %d bytes, which is more than the limit of %d",
+				table, len(s.row), limit-prefixLen)
+		}
+		if n > 0 && len(s.buf)+1+len(s.row) > limit {
+			if err = s.run(table, suffix, rows[i-n:i]); err != nil {
+				return err
+			}
+			s.buf, n = s.buf[:prefixLen], 0
+		}
+		if n > 0 {
+			s.buf = append(s.buf, ',')
+		}
+		s.buf = append(s.buf, s.row...)
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	return s.run(table, suffix, rows[len(rows)-n:])
+}
+
+// run executes the statement of the rows in buf followed by the suffix, retrying it after transient errors.
+func (s *DBSink) run(table, suffix string, rows [][]generators.Value) error {
+	query := string(s.buf) + suffix
+	var affected int64
+	retries, err := retry(table, s.MaxRetries, func() error {
+		var err error
+		affected, err = s.execQuery(query)
+		return err
+	})
+	s.stats.Retries += int64(retries)
+	if err != nil {
+		return err
+	}
+	s.stats.Bytes += int64(len(query))
+	s.stats.Affected += affected
+	if n := int64(len(rows)); s.Conflict.Action == config.ConflictIgnore && affected < n {
+		s.stats.Ignored += n - affected
+	}
+	if s.Written != nil {
+		s.Written(rows)
+	}
+	return nil
+}
+
+// retry calls f until it succeeds, fails with an error that isn't transient, or was retried the given number of times.
+// It returns the number of retries.
+func retry(table string, retries int, f func() error) (int, error) {
+	wait := retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil {
+			return attempt, nil
+		}
+		errorsTotal.With(table, errorClass(err)).Inc()
+		if attempt >= retries || !isTransient(err) {
+			return attempt, err
+		}
+		log.Printf("retrying a statement on %s in %s: %v", table, wait, err)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// isTransient tells whether the statement can succeed when executed again. Broken connections aren't, because the
+// statement may have been executed before the connection broke and running it again would insert the rows twice.
+func isTransient(err error) bool {
+	var myErr *mysql.MySQLError
+	return errors.As(err, &myErr) && (myErr.Number == 1205 || myErr.Number == 1213) // Lock wait timeout and deadlock.
+}
+
+// maxBytes returns MaxBytes, reading it from the server the first time if needed.
+func (s *DBSink) maxBytes() (int, error) {
+	if s.MaxBytes > 0 {
+		return s.MaxBytes, nil
+	}
+	var packet int
+	var err error
+	if s.Conn != nil {
+		err = s.Conn.QueryRowContext(context.Background(), "SELECT @@max_allowed_packet").Scan(&packet)
+	} else {
+		err = s.DB.QueryRow("SELECT @@max_allowed_packet").Scan(&packet)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("unable to read max_allowed_packet: %w", err)
+	}
+	s.MaxBytes = packet - packetMargin
+	log.Printf("limiting INSERT statements to %d bytes (max_allowed_packet is %d)", s.MaxBytes, packet)
+	return s.MaxBytes, nil
+}
+
+// appendInsert appends the INSERT statement for the rows to b.
+func appendInsert(b []byte, table string, columns []string, rows [][]generators.Value) []byte {
+	b = appendInsertPrefix(b, "INSERT INTO", table, columns)
+	for i, row := range rows {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendRow(b, row)
+	}
+	return b
+}
+
+// appendInsertPrefix appends the statement up to the rows, with the table and columns quoted, since masking copies
+// columns of real schemas whose names can be reserved words such as `order`.
+func appendInsertPrefix(b []byte, verb, table string, columns []string) []byte {
+	b = append(b, verb...)
+	b = append(b, ' ')
+	b = append(b, schema.QuoteIdent(table)...)
+	b = append(b, " ("...)
+	for i, col := range columns {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, schema.QuoteIdent(col)...)
+	}
+	return append(b, ") VALUES "...)
+}
+
+func appendRow(b []byte, row []generators.Value) []byte {
+	b = append(b, '(')
+	for j, v := range row {
+		if j > 0 {
+			b = append(b, ',')
+		}
+		b = v.AppendSQL(b)
+	}
+	return append(b, ')')
+}
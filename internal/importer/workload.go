@@ -0,0 +1,239 @@
+package importer
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/generators"
+	"github.com/bitstonks/syndi/internal/schema"
+)
+
+// Workload operations.
+const (
+	opInsert = "insert"
+	opUpdate = "update"
+	opDelete = "delete"
+)
+
+// maxKeys limits the keys kept for updates and deletes. Once reached, new keys replace random old ones.
+var maxKeys = 1000000
+
+// workload picks the operations of the load mode and the rows they target.
+type workload struct {
+	ops    generators.Generator
+	key    int   // Index of the key column.
+	update []int // Indexes of the updated columns.
+	keys   []generators.Value
+	rng    *rand.Rand
+}
+
+func newWorkload(def *config.WorkloadDef, cols config.Columns) (*workload, error) {
+	w := &workload{
+		ops: generators.NewQuotedOneOfGenerator(config.ColumnDef{OneOf: def.Operations}),
+		key: cols.Index(def.Key),
+		rng: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if w.key < 0 {
+		return nil, fmt.Errorf("workload key %q is not one of the columns", def.Key)
+	}
+	updates := false
+	for _, choice := range strings.Split(def.Operations, ";") {
+		op := strings.TrimSpace(strings.SplitN(choice, ":", 2)[0])
+		switch op {
+		case opUpdate:
+			updates = true
+		case opInsert, opDelete:
+		default:
+			return nil, fmt.Errorf("unknown workload operation %q, use one of: %s, %s, %s", op, opInsert, opUpdate,
+				opDelete)
+		}
+	}
+	for _, name := range def.Update {
+		i := cols.Index(name)
+		if i < 0 {
+			return nil, fmt.Errorf("updated column %q is not one of the columns", name)
+		}
+		if i == w.key {
+			return nil, fmt.Errorf("the workload key %q can't be updated", name)
+		}
+		w.update = append(w.update, i)
+	}
+	if updates && len(w.update) == 0 {
+		return nil, fmt.Errorf("workload has updates but no Update columns")
+	}
+	return w, nil
+}
+
+// next returns the next operation, inserting while there are no keys to update or delete.
+func (w *workload) next() string {
+	op := strings.TrimSpace(w.ops.Next().Str)
+	if len(w.keys) == 0 {
+		return opInsert
+	}
+	return op
+}
+
+// add adds a key of an inserted row.
+func (w *workload) add(key generators.Value) {
+	if key.Kind == generators.Null {
+		return
+	}
+	if len(w.keys) < maxKeys {
+		w.keys = append(w.keys, key)
+		return
+	}
+	w.keys[w.rng.Intn(len(w.keys))] = key
+}
+
+// pick returns up to n random keys, which may repeat.
+func (w *workload) pick(n int) []generators.Value {
+	if n > len(w.keys) {
+		n = len(w.keys)
+	}
+	keys := make([]generators.Value, 0, n)
+	for i := 0; i < n; i++ {
+		keys = append(keys, w.keys[w.rng.Intn(len(w.keys))])
+	}
+	return keys
+}
+
+// take removes up to n random keys and returns them.
+func (w *workload) take(n int) []generators.Value {
+	if n > len(w.keys) {
+		n = len(w.keys)
+	}
+	keys := make([]generators.Value, 0, n)
+	for i := 0; i < n; i++ {
+		j := w.rng.Intn(len(w.keys))
+		keys = append(keys, w.keys[j])
+		last := len(w.keys) - 1
+		w.keys[j] = w.keys[last]
+		w.keys = w.keys[:last]
+	}
+	return keys
+}
+
+// loadKeys adds keys of the newest rows already in the table, so that rows syndi didn't insert in this run are updated
+// and deleted too. Only used with LoadOptions.ExistingRows. Without a database there are none.
+func (im *Importer) loadKeys(w *workload) error {
+	if im.db == nil {
+		return nil
+	}
+	key := schema.QuoteIdent(im.cols[w.key])
+	rows, err := im.db.Query(fmt.Sprintf("SELECT %s FROM %s ORDER BY %s DESC LIMIT %d", key,
+		schema.QuoteIdent(im.cfg.TableName), key, maxKeys))
+	if err != nil {
+		return fmt.Errorf("unable to read keys of %s: %w", im.cfg.TableName, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v interface{}
+		if err = rows.Scan(&v); err != nil {
+			return err
+		}
+		w.add(scannedValue(v))
+	}
+	return rows.Err()
+}
+
+// step runs the next operation of the workload on size rows, or inserts them without a workload. The rows are reused
+// for inserts and returned.
+func (im *Importer) step(w *workload, rows [][]generators.Value, size int) ([][]generators.Value, error) {
+	op := opInsert
+	if w != nil {
+		op = w.next()
+	}
+	switch op {
+	case opUpdate:
+		return rows, im.update(w, size)
+	case opDelete:
+		return rows, im.delete(w, size)
+	}
+	rows = generateBatch(rows, size, im.gens)
+	rowsGenerated.With(im.cfg.TableName).Add(float64(size))
+	err := im.writeBatch(im.cols, rows)
+	if err == nil && w != nil {
+		for _, row := range rows {
+			w.add(row[w.key])
+		}
+	}
+	return rows, err
+}
+
+// update sets the Update columns of up to n random rows to newly generated values, one row per statement.
+func (im *Importer) update(w *workload, n int) error {
+	return im.execWorkload(opUpdate, im.updateStmts(w, w.pick(n)))
+}
+
+func (im *Importer) updateStmts(w *workload, keys []generators.Value) []string {
+	stmts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		b := []byte("UPDATE " + schema.QuoteIdent(im.cfg.TableName) + " SET ")
+		for i, col := range w.update {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, schema.QuoteIdent(im.cols[col])+"="...)
+			b = im.gens[col].Next().AppendSQL(b)
+		}
+		b = append(b, " WHERE "+schema.QuoteIdent(im.cols[w.key])+"="...)
+		stmts = append(stmts, string(key.AppendSQL(b)))
+	}
+	return stmts
+}
+
+// delete deletes up to n random rows with a single statement.
+func (im *Importer) delete(w *workload, n int) error {
+	return im.execWorkload(opDelete, []string{im.deleteStmt(w, w.take(n))})
+}
+
+func (im *Importer) deleteStmt(w *workload, keys []generators.Value) string {
+	b := []byte("DELETE FROM " + schema.QuoteIdent(im.cfg.TableName) + " WHERE " +
+		schema.QuoteIdent(im.cols[w.key]) + " IN (")
+	for i, key := range keys {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = key.AppendSQL(b)
+	}
+	return string(append(b, ')'))
+}
+
+// execWorkload executes the statements of an update or delete, retrying them after transient errors, and reports them
+// as a single batch.
+func (im *Importer) execWorkload(op string, stmts []string) error {
+	if im.db == nil {
+		return errNoDB
+	}
+	start := time.Now()
+	var affected, size int64
+	for _, stmt := range stmts {
+		retries, err := retry(im.cfg.TableName, DefaultRetries, func() error {
+			res, err := im.exec(stmt)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			affected += n
+			return err
+		})
+		for i := 0; i < retries && im.prog != nil; i++ {
+			im.prog.Retry()
+		}
+		if err != nil {
+			return err
+		}
+		size += int64(len(stmt))
+	}
+	latency := time.Since(start)
+	batchDuration.With(im.cfg.TableName).Observe(latency.Seconds())
+	workloadRows.With(im.cfg.TableName, op).Add(float64(affected))
+	if im.prog != nil {
+		im.prog.Batch(int(affected), int(size), latency)
+		im.prog.Result(affected, 0)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package importer
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/generators"
+	"github.com/stretchr/testify/assert"
+)
+
+var workloadColumns = config.Columns{
+	{Name: "id", ColumnDef: config.ColumnDef{Type: "int/incremental-uniform", First: "1", MinVal: "1", MaxVal: "2"}},
+	{Name: "status", ColumnDef: config.ColumnDef{Type: "string/oneof", OneOf: "done"}},
+}
+
+func TestNewWorkload(t *testing.T) {
+	for _, tc := range []struct {
+		def config.WorkloadDef
+		msg string
+	}{
+		{config.WorkloadDef{Key: "uid", Operations: "insert"}, `workload key "uid" is not one of the columns`},
+		{config.WorkloadDef{Key: "id", Operations: "insert:9;upsert"},
+			`unknown workload operation "upsert", use one of: insert, update, delete`},
+		{config.WorkloadDef{Key: "id", Operations: "insert;update"}, "workload has updates but no Update columns"},
+		{config.WorkloadDef{Key: "id", Operations: "update", Update: []string{"x"}},
+			`updated column "x" is not one of the columns`},
+	} {
+		_, err := newWorkload(&tc.def, workloadColumns)
+		assert.EqualError(t, err, tc.msg)
+	}
+	_, err := newWorkload(&config.WorkloadDef{Key: "id", Operations: "update", Update: []string{"id"}}, workloadColumns)
+	assert.EqualError(t, err, `the workload key "id" can't be updated`)
+}
+
+func TestWorkloadKeys(t *testing.T) {
+	w, err := newWorkload(&config.WorkloadDef{Key: "id", Operations: "delete"}, workloadColumns)
+	assert.NoError(t, err)
+	assert.Equal(t, opInsert, w.next(), "inserts while there are no keys")
+
+	defer func(n int) { maxKeys = n }(maxKeys)
+	maxKeys = 3
+	for i := int64(1); i <= 5; i++ {
+		w.add(generators.IntValue(i))
+	}
+	w.add(generators.NullValue())
+	assert.Len(t, w.keys, 3)
+	assert.Equal(t, opDelete, w.next())
+	assert.Len(t, w.pick(5), 3)
+	assert.Len(t, w.keys, 3)
+	taken := w.take(2)
+	assert.Len(t, taken, 2)
+	assert.Len(t, w.keys, 1)
+	assert.NotContains(t, w.keys, taken[0])
+	assert.Len(t, w.take(2), 1)
+	assert.Empty(t, w.keys)
+}
+
+func TestWorkloadStatements(t *testing.T) {
+	cfg := &config.TableDef{TableName: "orders", BatchSize: 10, Columns: workloadColumns}
+	im, err := NewSinkImporter(NewDBSink(nil), cfg)
+	assert.NoError(t, err)
+	w, err := newWorkload(&config.WorkloadDef{Key: "id", Operations: "update", Update: []string{"status"}},
+		cfg.Columns)
+	assert.NoError(t, err)
+	keys := []generators.Value{generators.IntValue(4), generators.IntValue(7)}
+	assert.Equal(t, []string{
+		"UPDATE `orders` SET `status`='done' WHERE `id`=4",
+		"UPDATE `orders` SET `status`='done' WHERE `id`=7",
+	}, im.updateStmts(w, keys))
+	assert.Equal(t, "DELETE FROM `orders` WHERE `id` IN (4,7)", im.deleteStmt(w, keys))
+}
+
+func TestLoadWorkloadWithoutDB(t *testing.T) {
+	sink := NewDBSink(nil)
+	sink.MaxBytes = 1000
+	sink.exec = func(query string) (int64, error) { return 0, nil }
+	cfg := &config.TableDef{TableName: "orders", BatchSize: 10, Columns: workloadColumns,
+		Workload: &config.WorkloadDef{Key: "id", Operations: "delete"}}
+	im, err := NewSinkImporter(sink, cfg)
+	assert.NoError(t, err)
+	err = im.Load(LoadOptions{Rate: 1000, Duration: time.Second}, nil)
+	assert.Equal(t, errNoDB, err)
+}
+
+func TestLoadWorkloadExistingRows(t *testing.T) {
+	db, err := sql.Open("flaky", "") // Can't query, so reading the keys of existing rows fails.
+	assert.NoError(t, err)
+	defer db.Close()
+	sink := NewDBSink(nil)
+	sink.MaxBytes = 1000
+	sink.exec = func(query string) (int64, error) { return 0, nil }
+	cfg := &config.TableDef{TableName: "orders", BatchSize: 10, Columns: workloadColumns,
+		Workload: &config.WorkloadDef{Key: "id", Operations: "insert"}}
+	im, err := NewSinkImporter(sink, cfg)
+	assert.NoError(t, err)
+	im.db = db
+
+	assert.NoError(t, im.Load(LoadOptions{Rate: 1000, Duration: 100 * time.Millisecond}, nil))
+	err = im.Load(LoadOptions{Rate: 1000, Duration: 100 * time.Millisecond, ExistingRows: true}, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to read keys of orders")
+}
@@ -0,0 +1,303 @@
+// Package manifest records the keys of the rows inserted by a run, so that exactly those rows can be deleted later.
+package manifest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitstonks/syndi/internal/generators"
+	"github.com/bitstonks/syndi/internal/schema"
+)
+
+// DefaultDir is where manifests are kept, relative to the working directory.
+const DefaultDir = ".syndi/runs"
+
+// Manifest is what a run inserted. While a run goes on, keys of the inserted rows are kept in memory only until the
+// next Flush appends them to the keys file next to the manifest, so a long run doesn't hold all of them and a run
+// that is killed loses at most the keys since the last Flush.
+type Manifest struct {
+	RunID   string    `json:"run_id"`
+	Created time.Time `json:"created"`
+	Tables  []*Table  `json:"tables"` // In the order of the import.
+
+	mu      sync.Mutex // Guards keys of the tables, which are added by the importers while the manifest is flushed.
+	flushed int        // Number of tables in the saved manifest file.
+}
+
+// Table holds the keys of the rows inserted into a table. Consecutive integer keys, e.g. of incremental columns, are
+// kept as ranges and other keys as SQL literals.
+type Table struct {
+	Name   string     `json:"table"`
+	Key    string     `json:"key"`
+	Ranges [][2]int64 `json:"ranges,omitempty"` // Inclusive.
+	Keys   []string   `json:"keys,omitempty"`
+
+	m *Manifest // Nil unless the table was added to a manifest.
+}
+
+// chunk holds the keys of a table appended to the keys file by a Flush.
+type chunk struct {
+	Table  int        `json:"table"` // Index in Tables.
+	Ranges [][2]int64 `json:"ranges,omitempty"`
+	Keys   []string   `json:"keys,omitempty"`
+}
+
+// NewRunID returns an ID starting with the time, so that IDs sort by the start of the runs.
+func NewRunID(now time.Time) string {
+	b := make([]byte, 3)
+	_, _ = rand.Read(b)
+	return now.Format("20060102-150405-") + hex.EncodeToString(b)
+}
+
+// New creates an empty manifest.
+func New(runID string, created time.Time) *Manifest {
+	return &Manifest{RunID: runID, Created: created}
+}
+
+// AddTable adds a table whose rows are identified by the key column.
+func (m *Manifest) AddTable(name, key string) *Table {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := &Table{Name: name, Key: key, m: m}
+	m.Tables = append(m.Tables, t)
+	return t
+}
+
+// Add records the key of an inserted row. It is safe to call while the manifest is flushed.
+func (t *Table) Add(key generators.Value) {
+	if t.m != nil {
+		t.m.mu.Lock()
+		defer t.m.mu.Unlock()
+	}
+	switch key.Kind {
+	case generators.Null:
+		return
+	case generators.Int:
+		if n := len(t.Ranges); n > 0 && t.Ranges[n-1][1] == key.Int-1 {
+			t.Ranges[n-1][1] = key.Int
+			return
+		}
+		t.Ranges = append(t.Ranges, [2]int64{key.Int, key.Int})
+		return
+	}
+	t.Keys = append(t.Keys, key.String())
+}
+
+// coalesce sorts the ranges and merges the adjacent ones, e.g. of random integer keys.
+func (t *Table) coalesce() {
+	sort.Slice(t.Ranges, func(i, j int) bool {
+		return t.Ranges[i][0] < t.Ranges[j][0]
+	})
+	merged := t.Ranges[:0]
+	for _, r := range t.Ranges {
+		if n := len(merged); n > 0 && r[0] <= merged[n-1][1]+1 {
+			if r[1] > merged[n-1][1] {
+				merged[n-1][1] = r[1]
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	t.Ranges = merged
+}
+
+// Rows returns the number of recorded keys.
+func (t *Table) Rows() int64 {
+	n := int64(len(t.Keys))
+	for _, r := range t.Ranges {
+		n += r[1] - r[0] + 1
+	}
+	return n
+}
+
+// path returns the file of the manifest with the run ID.
+func path(dir, runID string) string {
+	return filepath.Join(dir, runID+".json")
+}
+
+// keysPath returns the file the keys of the run are appended to.
+func keysPath(dir, runID string) string {
+	return filepath.Join(dir, runID+".keys")
+}
+
+// Flush saves the manifest to the directory and appends the keys recorded since the previous Flush to the keys file,
+// removing them from memory.
+func (m *Manifest) Flush(dir string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.flushed < len(m.Tables) {
+		if err := m.save(dir); err != nil {
+			return err
+		}
+		m.flushed = len(m.Tables)
+	}
+	var data []byte
+	for i, t := range m.Tables {
+		if len(t.Ranges) == 0 && len(t.Keys) == 0 {
+			continue
+		}
+		t.coalesce()
+		line, err := json.Marshal(chunk{Table: i, Ranges: t.Ranges, Keys: t.Keys})
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(keysPath(dir, m.RunID), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	for _, t := range m.Tables {
+		t.Ranges, t.Keys = nil, nil
+	}
+	return nil
+}
+
+// save writes the manifest without the keys to the directory, replacing the previous version.
+func (m *Manifest) save(dir string) error {
+	tables := make([]*Table, 0, len(m.Tables))
+	for _, t := range m.Tables {
+		tables = append(tables, &Table{Name: t.Name, Key: t.Key})
+	}
+	data, err := json.Marshal(&Manifest{RunID: m.RunID, Created: m.Created, Tables: tables})
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp := path(dir, m.RunID) + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path(dir, m.RunID))
+}
+
+// Load reads the manifest of the run from the directory, together with all its keys.
+func Load(dir, runID string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(path(dir, runID))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no manifest of run %s in %s", runID, dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err = json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path(dir, runID), err)
+	}
+	f, err := os.Open(keysPath(dir, runID))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	for {
+		var c chunk
+		err = dec.Decode(&c)
+		if err == io.EOF {
+			break
+		}
+		if err != nil || c.Table < 0 || c.Table >= len(m.Tables) {
+			// A run killed while appending can leave the last chunk incomplete.
+			log.Printf("%s: ignoring invalid keys from byte %d on", keysPath(dir, runID), dec.InputOffset())
+			break
+		}
+		t := m.Tables[c.Table]
+		t.Ranges = append(t.Ranges, c.Ranges...)
+		t.Keys = append(t.Keys, c.Keys...)
+	}
+	for _, t := range m.Tables {
+		t.coalesce()
+	}
+	return m, nil
+}
+
+// Remove deletes the manifest of the run and its keys from the directory.
+func Remove(dir, runID string) error {
+	err := os.Remove(keysPath(dir, runID))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Remove(path(dir, runID))
+}
+
+// Deletes calls fn with statements deleting the recorded rows, each at most batch rows. Ranges are deleted with
+// BETWEEN and the other keys with IN lists.
+func (t *Table) Deletes(batch int, fn func(stmt string) error) error {
+	table, key := schema.QuoteIdent(t.Name), schema.QuoteIdent(t.Key)
+	var keys []string
+	for _, r := range t.Ranges {
+		if r[0] == r[1] {
+			keys = append(keys, strconv.FormatInt(r[0], 10))
+			continue
+		}
+		for lo := r[0]; lo <= r[1]; lo += int64(batch) {
+			hi := lo + int64(batch) - 1
+			if hi > r[1] {
+				hi = r[1]
+			}
+			err := fn(fmt.Sprintf("DELETE FROM %s WHERE %s BETWEEN %d AND %d", table, key, lo, hi))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	keys = append(keys, t.Keys...)
+	for len(keys) > 0 {
+		n := batch
+		if n > len(keys) {
+			n = len(keys)
+		}
+		err := fn(fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s)", table, key, strings.Join(keys[:n], ",")))
+		if err != nil {
+			return err
+		}
+		keys = keys[n:]
+	}
+	return nil
+}
+
+// List returns IDs of the runs with manifests in the directory, oldest first.
+func List(dir string) ([]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, f := range files {
+		if name := f.Name(); !f.IsDir() && strings.HasSuffix(name, ".json") {
+			ids = append(ids, strings.TrimSuffix(name, ".json"))
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
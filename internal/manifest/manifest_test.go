@@ -0,0 +1,112 @@
+package manifest
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bitstonks/syndi/internal/generators"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableAdd(t *testing.T) {
+	tbl := &Table{Name: "t", Key: "id"}
+	for _, k := range []int64{1, 2, 3, 5, 7, 8} {
+		tbl.Add(generators.IntValue(k))
+	}
+	tbl.Add(generators.NullValue())
+	tbl.Add(generators.StringValue("it's"))
+	assert.Equal(t, [][2]int64{{1, 3}, {5, 5}, {7, 8}}, tbl.Ranges)
+	assert.Equal(t, []string{`'it\'s'`}, tbl.Keys)
+	assert.Equal(t, int64(7), tbl.Rows())
+}
+
+func TestDeletes(t *testing.T) {
+	tbl := &Table{Name: "order", Key: "id", Ranges: [][2]int64{{1, 5}, {7, 7}, {9, 9}}, Keys: []string{"'a'"}}
+	var stmts []string
+	assert.NoError(t, tbl.Deletes(2, func(stmt string) error {
+		stmts = append(stmts, stmt)
+		return nil
+	}))
+	assert.Equal(t, []string{
+		"DELETE FROM `order` WHERE `id` BETWEEN 1 AND 2",
+		"DELETE FROM `order` WHERE `id` BETWEEN 3 AND 4",
+		"DELETE FROM `order` WHERE `id` BETWEEN 5 AND 5",
+		"DELETE FROM `order` WHERE `id` IN (7,9)",
+		"DELETE FROM `order` WHERE `id` IN ('a')",
+	}, stmts)
+}
+
+func TestFlushLoad(t *testing.T) {
+	dir := t.TempDir()
+	ids, err := List(dir + "/missing")
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	created := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	m := New(NewRunID(created), created)
+	assert.Regexp(t, `^20210304-050607-[0-9a-f]{6}$`, m.RunID)
+	users := m.AddTable("users", "id")
+	for _, k := range []int64{7, 3, 1, 2} {
+		users.Add(generators.IntValue(k))
+	}
+	assert.NoError(t, m.Flush(dir))
+	assert.Empty(t, users.Ranges, "flushed keys are dropped from memory")
+	orders := m.AddTable("orders", "uuid")
+	orders.Add(generators.StringValue("x"))
+	for _, k := range []int64{5, 4, 6} {
+		users.Add(generators.IntValue(k))
+	}
+	assert.NoError(t, m.Flush(dir))
+	assert.NoError(t, m.Flush(dir))
+	orders.Add(generators.StringValue("y"))
+	assert.NoError(t, m.Flush(dir))
+
+	loaded, err := Load(dir, m.RunID)
+	assert.NoError(t, err)
+	assert.Equal(t, m.RunID, loaded.RunID)
+	assert.True(t, created.Equal(loaded.Created))
+	assert.Equal(t, []*Table{
+		{Name: "users", Key: "id", Ranges: [][2]int64{{1, 7}}},
+		{Name: "orders", Key: "uuid", Keys: []string{"'x'", "'y'"}},
+	}, loaded.Tables)
+	ids, err = List(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{m.RunID}, ids)
+
+	// A run killed while flushing leaves an incomplete chunk behind.
+	f, err := os.OpenFile(keysPath(dir, m.RunID), os.O_WRONLY|os.O_APPEND, 0)
+	assert.NoError(t, err)
+	_, err = f.WriteString(`{"table":0,"ranges":[[9`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+	loaded, err = Load(dir, m.RunID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), loaded.Tables[0].Rows()+loaded.Tables[1].Rows())
+
+	assert.NoError(t, Remove(dir, m.RunID))
+	_, err = Load(dir, m.RunID)
+	assert.EqualError(t, err, "no manifest of run "+m.RunID+" in "+dir)
+}
+
+func TestFlushConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	m := New("test", time.Now())
+	tbl := m.AddTable("users", "id")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for k := int64(1000); k > 0; k-- {
+			tbl.Add(generators.IntValue(k))
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, m.Flush(dir))
+	}
+	<-done
+	assert.NoError(t, m.Flush(dir))
+
+	loaded, err := Load(dir, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]int64{{1, 1000}}, loaded.Tables[0].Ranges)
+}
@@ -0,0 +1,244 @@
+// Package metrics keeps counters, gauges and histograms and exposes them in the Prometheus text format, so that
+// long imports can be scraped without any external service.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default is the registry the importer records its metrics to.
+var Default = &Registry{}
+
+// DefBuckets are histogram buckets for latencies in seconds.
+var DefBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds metrics and writes them in the order they were registered.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []*vec
+}
+
+// vec is a metric with a series for each combination of label values.
+type vec struct {
+	name    string
+	help    string
+	typ     string
+	labels  []string
+	buckets []float64 // Upper bounds of histogram buckets, without +Inf.
+	mu      sync.Mutex
+	series  map[string]*series
+}
+
+// series is a single time series of a metric.
+type series struct {
+	mu     sync.Mutex
+	labels []string
+	value  float64  // Value of counters and gauges, sum of histograms.
+	count  uint64   // Observations of histograms.
+	counts []uint64 // Observations in each histogram bucket, not cumulative.
+}
+
+func (r *Registry) register(name, help, typ string, buckets []float64, labels []string) *vec {
+	v := &vec{name: name, help: help, typ: typ, labels: labels, buckets: buckets, series: map[string]*series{}}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range r.metrics {
+		if m.name == name {
+			panic(fmt.Sprintf("metric %s registered twice", name))
+		}
+	}
+	r.metrics = append(r.metrics, v)
+	return v
+}
+
+func (v *vec) with(values []string) *series {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s has %d labels, got %d values", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labels: append([]string(nil), values...), counts: make([]uint64, len(v.buckets))}
+		v.series[key] = s
+	}
+	return s
+}
+
+// CounterVec is a counter with labels.
+type CounterVec struct{ v *vec }
+
+// Counter only goes up.
+type Counter struct{ s *series }
+
+// NewCounterVec registers a counter.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{r.register(name, help, "counter", nil, labels)}
+}
+
+// With returns the counter for the label values.
+func (c *CounterVec) With(values ...string) Counter {
+	return Counter{c.v.with(values)}
+}
+
+// Add adds n, which must not be negative.
+func (c Counter) Add(n float64) {
+	c.s.mu.Lock()
+	c.s.value += n
+	c.s.mu.Unlock()
+}
+
+// Inc adds one.
+func (c Counter) Inc() {
+	c.Add(1)
+}
+
+// GaugeVec is a gauge with labels.
+type GaugeVec struct{ v *vec }
+
+// Gauge goes up and down.
+type Gauge struct{ s *series }
+
+// NewGaugeVec registers a gauge.
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{r.register(name, help, "gauge", nil, labels)}
+}
+
+// With returns the gauge for the label values.
+func (g *GaugeVec) With(values ...string) Gauge {
+	return Gauge{g.v.with(values)}
+}
+
+// Set sets the value.
+func (g Gauge) Set(n float64) {
+	g.s.mu.Lock()
+	g.s.value = n
+	g.s.mu.Unlock()
+}
+
+// HistogramVec is a histogram with labels.
+type HistogramVec struct{ v *vec }
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	s       *series
+	buckets []float64
+}
+
+// NewHistogramVec registers a histogram with the bucket upper bounds given in increasing order.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{r.register(name, help, "histogram", buckets, labels)}
+}
+
+// With returns the histogram for the label values.
+func (h *HistogramVec) With(values ...string) Histogram {
+	return Histogram{h.v.with(values), h.v.buckets}
+}
+
+// Observe adds an observation.
+func (h Histogram) Observe(n float64) {
+	i := sort.SearchFloat64s(h.buckets, n)
+	h.s.mu.Lock()
+	defer h.s.mu.Unlock()
+	if i < len(h.buckets) {
+		h.s.counts[i]++
+	}
+	h.s.count++
+	h.s.value += n
+}
+
+// Write writes all metrics in the Prometheus text exposition format.
+func (r *Registry) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	r.mu.Lock()
+	metrics := append([]*vec(nil), r.metrics...)
+	r.mu.Unlock()
+	for _, v := range metrics {
+		v.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the metrics over HTTP.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.Write(w)
+	})
+}
+
+func (v *vec) write(w *bufio.Writer) {
+	v.mu.Lock()
+	all := make([]*series, 0, len(v.series))
+	for _, s := range v.series {
+		all = append(all, s)
+	}
+	v.mu.Unlock()
+	sort.Slice(all, func(i, j int) bool {
+		return strings.Join(all[i].labels, "\xff") < strings.Join(all[j].labels, "\xff")
+	})
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, escapeHelp(v.help), v.name, v.typ)
+	for _, s := range all {
+		s.mu.Lock()
+		labels := formatLabels(v.labels, s.labels)
+		if v.typ != "histogram" {
+			fmt.Fprintf(w, "%s%s %s\n", v.name, wrapLabels(labels), formatValue(s.value))
+			s.mu.Unlock()
+			continue
+		}
+		var cumulative uint64
+		for i, upper := range v.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, wrapLabels(labels, "le", formatValue(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, wrapLabels(labels, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.name, wrapLabels(labels), formatValue(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.name, wrapLabels(labels), s.count)
+		s.mu.Unlock()
+	}
+}
+
+// formatLabels returns name="value" pairs.
+func formatLabels(names, values []string) []string {
+	pairs := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		pairs = append(pairs, name+`="`+labelEscaper.Replace(values[i])+`"`)
+	}
+	return pairs
+}
+
+// wrapLabels puts the pairs in braces, adding the extra name and value if given.
+func wrapLabels(pairs []string, extra ...string) string {
+	if len(extra) == 2 {
+		pairs = append(pairs[:len(pairs):len(pairs)], extra[0]+`="`+extra[1]+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func formatValue(n float64) string {
+	switch {
+	case math.IsInf(n, 1):
+		return "+Inf"
+	case math.IsInf(n, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(n, 'g', -1, 64)
+}
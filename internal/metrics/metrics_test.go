@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	r := &Registry{}
+	rows := r.NewCounterVec("rows_total", "Rows inserted.", "table")
+	depth := r.NewGaugeVec("queue_depth", "Batches waiting.")
+	latency := r.NewHistogramVec("latency_seconds", "Batch latency.", []float64{0.1, 1}, "table")
+	rows.With("users").Add(10)
+	rows.With("orders").Inc()
+	rows.With(`we"ird`).Inc()
+	depth.With().Set(2)
+	latency.With("users").Observe(0.05)
+	latency.With("users").Observe(0.5)
+	latency.With("users").Observe(3)
+
+	srv := httptest.NewServer(r.Handler())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `# HELP rows_total Rows inserted.
+# TYPE rows_total counter
+rows_total{table="orders"} 1
+rows_total{table="users"} 10
+rows_total{table="we\"ird"} 1
+# HELP queue_depth Batches waiting.
+# TYPE queue_depth gauge
+queue_depth 2
+# HELP latency_seconds Batch latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{table="users",le="0.1"} 1
+latency_seconds_bucket{table="users",le="1"} 2
+latency_seconds_bucket{table="users",le="+Inf"} 3
+latency_seconds_sum{table="users"} 3.55
+latency_seconds_count{table="users"} 3
+`, string(body))
+}
+
+func TestRegisterTwice(t *testing.T) {
+	r := &Registry{}
+	r.NewCounterVec("a", "")
+	assert.Panics(t, func() { r.NewGaugeVec("a", "") })
+}
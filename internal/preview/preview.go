@@ -0,0 +1,237 @@
+// Package preview generates rows without a database and summarizes them, so configs can be checked while writing them.
+package preview
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/generators"
+)
+
+const (
+	topValues    = 3  // Number of the most frequent values listed in stats.
+	maxCellWidth = 40 // Longer values are cut in tables.
+)
+
+// Value is a single generated value prepared for display.
+type Value struct {
+	Text   string // Unquoted value.
+	Null   bool
+	Number bool // Whether the value is a number.
+}
+
+func (v Value) String() string {
+	if v.Null {
+		return "NULL"
+	}
+	return v.Text
+}
+
+// MarshalJSON writes numbers as numbers and NULLs as null.
+func (v Value) MarshalJSON() ([]byte, error) {
+	switch {
+	case v.Null:
+		return []byte("null"), nil
+	case v.Number:
+		return []byte(v.Text), nil
+	}
+	return json.Marshal(v.Text)
+}
+
+func displayValue(v generators.Value) Value {
+	switch v.Kind {
+	case generators.Null:
+		return Value{Null: true}
+	case generators.Int, generators.Float:
+		return Value{Text: v.String(), Number: true}
+	case generators.String:
+		return Value{Text: v.Str}
+	case generators.Time:
+		return Value{Text: v.Time.Format(v.Layout())}
+	}
+	// Raw SQL, usually a custom Format, which can produce quoted strings or numbers.
+	s := v.Str
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return Value{Text: s[1 : len(s)-1]}
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return Value{Text: s, Number: err == nil}
+}
+
+// Generate creates n rows of the table.
+func Generate(tdef *config.TableDef, n int) ([][]Value, error) {
+	gens := make([]generators.Generator, 0, len(tdef.Columns))
+	for _, col := range tdef.Columns {
+		col.Rows = n
+		g, err := generators.GetGenerator(col.ColumnDef)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		gens = append(gens, g)
+	}
+	rows := make([][]Value, 0, n)
+	for i := 0; i < n; i++ {
+		row := make([]Value, 0, len(gens))
+		for _, g := range gens {
+			row = append(row, displayValue(g.Next()))
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// WriteTable writes the rows as an aligned text table.
+func WriteTable(w io.Writer, names []string, rows [][]Value) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(names, "\t"))
+	for _, row := range rows {
+		cells := make([]string, 0, len(row))
+		for _, v := range row {
+			cells = append(cells, truncate(v.String()))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the rows as JSON objects with keys in column order, one per line.
+func WriteJSON(w io.Writer, names []string, rows [][]Value) error {
+	for _, row := range rows {
+		var b bytes.Buffer
+		b.WriteByte('{')
+		for i, v := range row {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			key, _ := json.Marshal(names[i])
+			val, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			b.Write(key)
+			b.WriteByte(':')
+			b.Write(val)
+		}
+		b.WriteString("}\n")
+		if _, err := w.Write(b.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValueCount is a value with the number of times it was generated.
+type ValueCount struct {
+	Value string
+	Count int
+}
+
+// Stats summarizes the generated values of a single column.
+type Stats struct {
+	Name     string
+	Count    int
+	Nulls    int
+	Distinct int
+	Numeric  bool // Whether all non-NULL values are numbers, so Min, Max and Mean are set.
+	Min      float64
+	Max      float64
+	Mean     float64
+	Top      []ValueCount // The most frequent values.
+}
+
+// ColumnStats computes stats of each column.
+func ColumnStats(names []string, rows [][]Value) []Stats {
+	stats := make([]Stats, 0, len(names))
+	for i, name := range names {
+		s := Stats{Name: name, Count: len(rows), Numeric: true, Min: math.Inf(1), Max: math.Inf(-1)}
+		counts := map[string]int{}
+		sum := 0.0
+		for _, row := range rows {
+			v := row[i]
+			if v.Null {
+				s.Nulls++
+				continue
+			}
+			counts[v.Text]++
+			n, err := strconv.ParseFloat(v.Text, 64)
+			if err != nil {
+				s.Numeric = false
+				continue
+			}
+			s.Min, s.Max = math.Min(s.Min, n), math.Max(s.Max, n)
+			sum += n
+		}
+		if s.Nulls == s.Count {
+			s.Numeric = false
+		}
+		if s.Numeric {
+			s.Mean = sum / float64(s.Count-s.Nulls)
+		} else {
+			s.Min, s.Max = 0, 0
+		}
+		s.Distinct = len(counts)
+		for v, n := range counts {
+			s.Top = append(s.Top, ValueCount{v, n})
+		}
+		sort.Slice(s.Top, func(i, j int) bool {
+			if s.Top[i].Count != s.Top[j].Count {
+				return s.Top[i].Count > s.Top[j].Count
+			}
+			return s.Top[i].Value < s.Top[j].Value
+		})
+		if len(s.Top) > topValues {
+			s.Top = s.Top[:topValues]
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// WriteStats writes the stats as an aligned text table.
+func WriteStats(w io.Writer, stats []Stats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "column\tnull %\tdistinct\tmin\tmax\tmean\ttop values")
+	for _, s := range stats {
+		minVal, maxVal, mean := "", "", ""
+		if s.Numeric {
+			minVal, maxVal, mean = formatStat(s.Min), formatStat(s.Max), formatStat(s.Mean)
+		}
+		top := make([]string, 0, len(s.Top))
+		for _, vc := range s.Top {
+			top = append(top, fmt.Sprintf("%s (%.1f%%)", truncate(vc.Value), percent(vc.Count, s.Count)))
+		}
+		fmt.Fprintf(tw, "%s\t%.1f\t%d\t%s\t%s\t%s\t%s\n", s.Name, percent(s.Nulls, s.Count), s.Distinct,
+			minVal, maxVal, mean, strings.Join(top, ", "))
+	}
+	return tw.Flush()
+}
+
+var controlEscaper = strings.NewReplacer("\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// truncate escapes line breaks and tabs and cuts long values, so they fit into a table cell.
+func truncate(s string) string {
+	r := []rune(controlEscaper.Replace(s))
+	if len(r) <= maxCellWidth {
+		return string(r)
+	}
+	return string(r[:maxCellWidth-1]) + "…"
+}
+
+func formatStat(v float64) string {
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
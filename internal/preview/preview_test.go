@@ -0,0 +1,62 @@
+package preview
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/generators"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	names = []string{"id", "name", "score"}
+	rows  = [][]Value{
+		{displayValue(generators.IntValue(1)), displayValue(generators.StringValue("Ann")), displayValue(generators.RawValue("2.50"))},
+		{displayValue(generators.IntValue(2)), displayValue(generators.StringValue("Bob\nBobby")), displayValue(generators.NullValue())},
+		{displayValue(generators.IntValue(3)), displayValue(generators.RawValue("'Ann'")), displayValue(generators.FloatValue(4.5))},
+	}
+)
+
+func ExampleWriteTable() {
+	_ = WriteTable(os.Stdout, names, rows)
+	// Output:
+	// id  name        score
+	// 1   Ann         2.50
+	// 2   Bob\nBobby  NULL
+	// 3   Ann         4.5
+}
+
+func ExampleWriteJSON() {
+	_ = WriteJSON(os.Stdout, names, rows)
+	// Output:
+	// {"id":1,"name":"Ann","score":2.50}
+	// {"id":2,"name":"Bob\nBobby","score":null}
+	// {"id":3,"name":"Ann","score":4.5}
+}
+
+func ExampleWriteStats() {
+	_ = WriteStats(os.Stdout, ColumnStats(names, rows))
+	// Output:
+	// column  null %  distinct  min  max  mean  top values
+	// id      0.0     3         1    3    2     1 (33.3%), 2 (33.3%), 3 (33.3%)
+	// name    0.0     2                         Ann (66.7%), Bob\nBobby (33.3%)
+	// score   33.3    2         2.5  4.5  3.5   2.50 (33.3%), 4.5 (33.3%)
+}
+
+func TestGenerate(t *testing.T) {
+	tdef := &config.TableDef{Columns: config.Columns{
+		{Name: "id", ColumnDef: config.ColumnDef{Type: "int/incremental-uniform", First: "1", MinVal: "1", MaxVal: "2"}},
+		{Name: "role", ColumnDef: config.ColumnDef{Type: "string/oneof", OneOf: "user"}},
+	}}
+	rows, err := Generate(tdef, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]Value{
+		{{Text: "1", Number: true}, {Text: "user"}},
+		{{Text: "2", Number: true}, {Text: "user"}},
+	}, rows)
+
+	tdef.Columns[1].Type = "string/unknown"
+	_, err = Generate(tdef, 2)
+	assert.EqualError(t, err, "column role: generator of type string/unknown doesn't exist")
+}
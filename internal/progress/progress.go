@@ -0,0 +1,346 @@
+// Package progress tracks how far the import got and reports it while running and in a summary at the end.
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Modes of reporting progress while importing.
+const (
+	Text = "text" // A line updated in place on terminals, or printed every interval otherwise.
+	JSON = "json" // A JSON object per interval.
+	None = "none" // Nothing until the summary.
+)
+
+// Table is the progress of a single table.
+type Table struct {
+	tracker  *Tracker
+	Name     string
+	Total    int // Rows to insert, zero if unknown.
+	Rows     int64
+	Bytes    int64
+	Batches  int64
+	Retries  int64
+	Affected int64         // Rows affected as reported by the database.
+	Ignored  int64         // Rows skipped because of conflicts.
+	Latency  time.Duration // Sum of batch latencies.
+	Started  time.Time
+	Ended    time.Time
+}
+
+// Batch records a written batch.
+func (t *Table) Batch(rows, bytes int, latency time.Duration) {
+	t.tracker.mu.Lock()
+	defer t.tracker.mu.Unlock()
+	t.Rows += int64(rows)
+	t.Bytes += int64(bytes)
+	t.Batches++
+	t.Latency += latency
+}
+
+// Result records the rows affected and ignored by the written batches.
+func (t *Table) Result(affected, ignored int64) {
+	t.tracker.mu.Lock()
+	defer t.tracker.mu.Unlock()
+	t.Affected += affected
+	t.Ignored += ignored
+}
+
+// Retry records a retried statement.
+func (t *Table) Retry() {
+	t.tracker.mu.Lock()
+	defer t.tracker.mu.Unlock()
+	t.Retries++
+}
+
+// Begin marks the table as being imported, tables may be imported concurrently.
+func (t *Table) Begin() {
+	t.tracker.mu.Lock()
+	defer t.tracker.mu.Unlock()
+	t.Started = time.Now()
+	t.tracker.running = append(t.tracker.running, t)
+}
+
+// Done marks the table as imported and reports its final progress.
+func (t *Table) Done() {
+	t.tracker.mu.Lock()
+	defer t.tracker.mu.Unlock()
+	t.Ended = time.Now()
+	t.tracker.report(t)
+	running := t.tracker.running[:0]
+	for _, r := range t.tracker.running {
+		if r != t {
+			running = append(running, r)
+		}
+	}
+	t.tracker.running = running
+}
+
+func (t *Table) elapsed(now time.Time) time.Duration {
+	if t.Started.IsZero() {
+		return 0
+	}
+	if !t.Ended.IsZero() {
+		now = t.Ended
+	}
+	return now.Sub(t.Started)
+}
+
+// Tracker collects the progress of all tables and reports it periodically.
+type Tracker struct {
+	mu       sync.Mutex
+	w        io.Writer
+	mode     string
+	live     bool // Whether the text line is updated in place.
+	interval time.Duration
+	tables   []*Table
+	running  []*Table
+	started  time.Time
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewTracker creates a tracker reporting to w in the given mode.
+func NewTracker(w io.Writer, mode string, interval time.Duration) (*Tracker, error) {
+	if mode != Text && mode != JSON && mode != None {
+		return nil, fmt.Errorf("unknown progress mode %q, use one of: %s, %s, %s", mode, Text, JSON, None)
+	}
+	t := &Tracker{w: w, mode: mode, interval: interval}
+	if f, ok := w.(*os.File); ok && mode == Text {
+		if fi, err := f.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+			t.live = true
+		}
+	}
+	return t, nil
+}
+
+// AddTable adds a table to track, total is the number of rows to insert or zero if unknown.
+func (t *Tracker) AddTable(name string, total int) *Table {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tbl := &Table{tracker: t, Name: name, Total: total}
+	t.tables = append(t.tables, tbl)
+	return tbl
+}
+
+// Start starts reporting every interval.
+func (t *Tracker) Start() {
+	t.started = time.Now()
+	t.stop, t.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(t.done)
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.mu.Lock()
+				t.report(nil)
+				t.mu.Unlock()
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops reporting.
+func (t *Tracker) Stop() {
+	if t.stop == nil {
+		return
+	}
+	close(t.stop)
+	<-t.done
+	t.stop = nil
+}
+
+// Status is a snapshot of the progress of a table and overall.
+type Status struct {
+	Table          string  `json:"table"`
+	Rows           int64   `json:"rows"`
+	Total          int     `json:"total,omitempty"`
+	Percent        float64 `json:"percent,omitempty"`
+	RowsPerSec     float64 `json:"rows_per_sec"`
+	BytesPerSec    float64 `json:"bytes_per_sec"`
+	ETASec         float64 `json:"eta_sec,omitempty"`
+	OverallRows    int64   `json:"overall_rows"`
+	OverallTotal   int     `json:"overall_total,omitempty"`
+	OverallPercent float64 `json:"overall_percent,omitempty"`
+	OverallETASec  float64 `json:"overall_eta_sec,omitempty"`
+	Done           bool    `json:"done,omitempty"`
+}
+
+// status returns the status of the table, the caller must hold the lock.
+func (t *Tracker) status(c *Table, now time.Time) Status {
+	s := Status{Table: c.Name, Rows: c.Rows, Total: c.Total}
+	if secs := c.elapsed(now).Seconds(); secs > 0 {
+		s.RowsPerSec = float64(c.Rows) / secs
+		s.BytesPerSec = float64(c.Bytes) / secs
+	}
+	s.Percent, s.ETASec = estimate(c.Rows, c.Total, s.RowsPerSec)
+	var first time.Time
+	for _, tbl := range t.tables {
+		s.OverallRows += tbl.Rows
+		s.OverallTotal += tbl.Total
+		if !tbl.Started.IsZero() && (first.IsZero() || tbl.Started.Before(first)) {
+			first = tbl.Started
+		}
+	}
+	if secs := now.Sub(first).Seconds(); !first.IsZero() && secs > 0 {
+		s.OverallPercent, s.OverallETASec = estimate(s.OverallRows, s.OverallTotal, float64(s.OverallRows)/secs)
+	}
+	return s
+}
+
+// estimate returns the percentage done and the remaining seconds, zeros if they are unknown.
+func estimate(rows int64, total int, rate float64) (float64, float64) {
+	if total <= 0 {
+		return 0, 0
+	}
+	percent := math.Min(100, float64(rows)/float64(total)*100)
+	if rate <= 0 {
+		return percent, 0
+	}
+	return percent, math.Max(0, float64(int64(total)-rows)/rate)
+}
+
+// report writes the status of the finished table, or of all running tables if done is nil. The caller must hold the
+// lock.
+func (t *Tracker) report(done *Table) {
+	tables := t.running
+	if done != nil {
+		tables = []*Table{done}
+	}
+	if t.mode == None || len(tables) == 0 {
+		return
+	}
+	now := time.Now()
+	lines := make([]string, 0, len(tables))
+	for _, tbl := range tables {
+		s := t.status(tbl, now)
+		s.Done = done != nil
+		if t.mode == JSON {
+			data, _ := json.Marshal(s)
+			fmt.Fprintf(t.w, "%s\n", data)
+			continue
+		}
+		lines = append(lines, formatStatus(s))
+	}
+	switch {
+	case t.mode == JSON:
+	case t.live && done != nil:
+		fmt.Fprintf(t.w, "\r%s\033[K\n", lines[0])
+	case t.live:
+		fmt.Fprintf(t.w, "\r%s\033[K", strings.Join(lines, "; "))
+	default:
+		fmt.Fprintln(t.w, strings.Join(lines, "\n"))
+	}
+}
+
+func formatStatus(s Status) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d", s.Table, s.Rows)
+	if s.Total > 0 {
+		fmt.Fprintf(&b, "/%d rows (%.1f%%)", s.Total, s.Percent)
+	} else {
+		b.WriteString(" rows")
+	}
+	fmt.Fprintf(&b, ", %.0f rows/s, %s/s", s.RowsPerSec, formatBytes(s.BytesPerSec))
+	if s.ETASec > 0 {
+		fmt.Fprintf(&b, ", ETA %s", formatDuration(s.ETASec))
+	}
+	if s.OverallTotal > 0 {
+		fmt.Fprintf(&b, " | overall %.1f%%", s.OverallPercent)
+		if s.OverallETASec > 0 {
+			fmt.Fprintf(&b, ", ETA %s", formatDuration(s.OverallETASec))
+		}
+	}
+	return b.String()
+}
+
+func formatBytes(n float64) string {
+	units := []string{"B", "KB", "MB", "GB"}
+	i := 0
+	for n >= 1000 && i < len(units)-1 {
+		n /= 1000
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", n, units[i])
+}
+
+func formatDuration(secs float64) string {
+	return (time.Duration(secs) * time.Second).String()
+}
+
+// Summary is the outcome of importing a table.
+type Summary struct {
+	Table      string        `json:"table"`
+	Rows       int64         `json:"rows"`
+	Affected   int64         `json:"affected"`
+	Ignored    int64         `json:"ignored"`
+	Batches    int64         `json:"batches"`
+	Retries    int64         `json:"retries"`
+	Elapsed    time.Duration `json:"elapsed_ns"`
+	AvgLatency time.Duration `json:"avg_latency_ns"`
+}
+
+// Summaries returns the summary of each table followed by the total.
+func (t *Tracker) Summaries() []Summary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	sums := make([]Summary, 0, len(t.tables)+1)
+	total := Table{Name: "total"}
+	for _, tbl := range t.tables {
+		sums = append(sums, tbl.summary(tbl.elapsed(now)))
+		total.Rows += tbl.Rows
+		total.Affected += tbl.Affected
+		total.Ignored += tbl.Ignored
+		total.Batches += tbl.Batches
+		total.Retries += tbl.Retries
+		total.Latency += tbl.Latency
+	}
+	var elapsed time.Duration
+	if !t.started.IsZero() {
+		elapsed = now.Sub(t.started)
+	}
+	return append(sums, total.summary(elapsed))
+}
+
+func (t *Table) summary(elapsed time.Duration) Summary {
+	s := Summary{Table: t.Name, Rows: t.Rows, Affected: t.Affected, Ignored: t.Ignored, Batches: t.Batches,
+		Retries: t.Retries, Elapsed: elapsed}
+	if t.Batches > 0 {
+		s.AvgLatency = t.Latency / time.Duration(t.Batches)
+	}
+	return s
+}
+
+// WriteSummary writes the summaries as an aligned text table, or as JSON objects one per line in the JSON mode.
+func (t *Tracker) WriteSummary(w io.Writer) error {
+	sums := t.Summaries()
+	if t.mode == JSON {
+		enc := json.NewEncoder(w)
+		for _, s := range sums {
+			if err := enc.Encode(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "table\trows\taffected\tignored\tbatches\tretries\telapsed\tavg latency\t")
+	for _, s := range sums {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%s\t%s\t\n", s.Table, s.Rows, s.Affected, s.Ignored, s.Batches,
+			s.Retries, s.Elapsed.Round(time.Millisecond), s.AvgLatency.Round(time.Microsecond))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,53 @@
+package progress
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus(t *testing.T) {
+	tr, err := NewTracker(&strings.Builder{}, Text, time.Hour)
+	assert.NoError(t, err)
+	a := tr.AddTable("a", 100)
+	b := tr.AddTable("b", 300)
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	a.Started, a.Ended, a.Rows = start, start.Add(10*time.Second), 100
+	b.Started, b.Rows, b.Bytes = start.Add(10*time.Second), 100, 5000
+
+	s := tr.status(b, start.Add(20*time.Second))
+	assert.Equal(t, "b", s.Table)
+	assert.InDelta(t, 33.3, s.Percent, 0.1)
+	assert.Equal(t, 10.0, s.RowsPerSec)
+	assert.Equal(t, 500.0, s.BytesPerSec)
+	assert.Equal(t, 20.0, s.ETASec)
+	assert.Equal(t, int64(200), s.OverallRows)
+	assert.Equal(t, 50.0, s.OverallPercent)
+	assert.Equal(t, 20.0, s.OverallETASec)
+	assert.Equal(t, "b: 100/300 rows (33.3%), 10 rows/s, 500.0 B/s, ETA 20s | overall 50.0%, ETA 20s",
+		formatStatus(s))
+}
+
+func TestNewTrackerUnknownMode(t *testing.T) {
+	_, err := NewTracker(&strings.Builder{}, "xml", time.Second)
+	assert.EqualError(t, err, `unknown progress mode "xml", use one of: text, json, none`)
+}
+
+func TestWriteSummary(t *testing.T) {
+	var out strings.Builder
+	tr, _ := NewTracker(&out, JSON, time.Hour)
+	a := tr.AddTable("a", 10)
+	a.Batch(6, 100, 2*time.Millisecond)
+	a.Batch(4, 80, 4*time.Millisecond)
+	a.Retry()
+	sums := tr.Summaries()
+	assert.Len(t, sums, 2)
+	assert.Equal(t, Summary{Table: "a", Rows: 10, Batches: 2, Retries: 1, AvgLatency: 3 * time.Millisecond}, sums[0])
+	assert.Equal(t, "total", sums[1].Table)
+	assert.Equal(t, int64(10), sums[1].Rows)
+
+	assert.NoError(t, tr.WriteSummary(&out))
+	assert.Contains(t, out.String(), `{"table":"a","rows":10,"affected":0,"ignored":0,"batches":2,"retries":1,"elapsed_ns":0,"avg_latency_ns":3000000}`)
+}
@@ -0,0 +1,299 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/generators"
+)
+
+// TIMESTAMP columns can only hold values from this range.
+var (
+	minTimestamp = time.Date(1970, 1, 1, 0, 0, 1, 0, time.UTC)
+	maxTimestamp = time.Date(2038, 1, 19, 3, 14, 7, 0, time.UTC)
+)
+
+// domain describes the values a generator can produce.
+type domain struct {
+	kind     string   // int, float, string, datetime, date or time
+	hasRange bool     // Whether min and max are known.
+	min, max float64  // Range of numbers (or unix timestamps), the upper bound is exclusive for floats. Infinite if unbounded.
+	maxLen   int      // Maximum length of strings, zero if unknown.
+	values   []string // All possible values if there is a finite set of them.
+}
+
+// generatorDomain derives the domain of the generator from its config. It returns false if the values can't be
+// predicted, e.g. because they are formatted with a custom Format.
+func generatorDomain(def config.ColumnDef, totalRecords int) (domain, bool) {
+	if def.Format != "" {
+		return domain{}, false
+	}
+	kind := def.Type
+	if i := strings.Index(kind, "/"); i >= 0 {
+		kind = kind[:i]
+	}
+	d := domain{kind: kind}
+	switch def.Type {
+	case "bool":
+		d.kind = "int"
+		d.values = []string{"0", "1"}
+	case "oneof", "bool/oneof", "int/oneof", "float/oneof", "string/oneof", "datetime/oneof":
+		for _, opt := range strings.Split(def.OneOf, ";") {
+			parts := strings.Split(opt, ":")
+			if _, err := strconv.Atoi(parts[len(parts)-1]); len(parts) > 1 && err == nil {
+				opt = strings.Join(parts[:len(parts)-1], ":")
+			}
+			d.values = append(d.values, opt)
+		}
+		if d.kind == "bool" {
+			d.kind = "int"
+		}
+	case "int", "int/uniform", "float", "float/uniform":
+		minVal, err1 := strconv.ParseFloat(def.MinVal, 64)
+		maxVal, err2 := strconv.ParseFloat(def.MaxVal, 64)
+		if err1 != nil || err2 != nil {
+			return d, false
+		}
+		d.min, d.max, d.hasRange = minVal, maxVal, true
+		if d.kind == "int" {
+			d.max--
+		}
+	case "int/incremental-uniform":
+		first, err1 := strconv.ParseFloat(def.First, 64)
+		maxStep, err2 := strconv.ParseFloat(def.MaxVal, 64)
+		if err1 != nil || err2 != nil {
+			return d, false
+		}
+		d.min, d.max, d.hasRange = first, first+float64(totalRecords-1)*(maxStep-1), true
+	case "float/exp":
+		minVal, err := strconv.ParseFloat(def.MinVal, 64)
+		if err != nil {
+			return d, false
+		}
+		d.min, d.max, d.hasRange = minVal, math.Inf(1), true
+	case "int/histogram", "float/histogram":
+		d.min, d.max, d.hasRange = histogramRange(def.OneOf)
+		if d.kind == "int" {
+			d.max--
+		}
+	case "float/randomwalk":
+		d.min, d.max, d.hasRange = 0, math.Inf(1), true
+		if v, err := strconv.ParseFloat(def.MinVal, 64); err == nil {
+			d.min = v
+		}
+		if v, err := strconv.ParseFloat(def.MaxVal, 64); err == nil {
+			d.max = v
+		}
+	case "float/normal":
+		// Unbounded on both sides, so only the kind is checked.
+	case "string", "string/rand", "string/text":
+		d.maxLen = def.Length
+		if def.Lengths != "" {
+			_, maxLen, _ := histogramRange(def.Lengths)
+			d.maxLen = int(maxLen) - 1
+		}
+	case "string/uuid":
+		d.maxLen = 36
+	case "datetime/uniform", "date/uniform", "datetime/seasonal", "date/seasonal":
+		minVal, maxVal, err := generators.TimeBounds(def)
+		if err != nil {
+			return d, false
+		}
+		d = timeDomain(d, def.Epoch, minVal, maxVal.Add(-time.Second))
+	case "datetime/incremental", "date/incremental":
+		first := def.First
+		if first == "" {
+			first = "now"
+		}
+		minVal, err := generators.ParseTime(def, first)
+		if err != nil || def.Rate != "" {
+			return d, false
+		}
+		step := def.MaxVal
+		if step == "" {
+			step = def.MinVal
+		}
+		maxStep := time.Second
+		if d.kind == "date" {
+			maxStep = 24 * time.Hour
+		}
+		if step != "" {
+			if maxStep, err = generators.ParseDuration(step); err != nil {
+				return d, false
+			}
+		}
+		d = timeDomain(d, def.Epoch, minVal, minVal.Add(time.Duration(totalRecords-1)*maxStep))
+	case "time/uniform":
+	default:
+		return d, false
+	}
+	for _, v := range d.values {
+		if n := utf8.RuneCountInString(v); n > d.maxLen {
+			d.maxLen = n
+		}
+	}
+	return d, true
+}
+
+// timeDomain sets the range of the datetime domain d, which becomes an integer one for Unix epoch values.
+func timeDomain(d domain, epoch string, minVal, maxVal time.Time) domain {
+	d.min, d.max, d.hasRange = float64(minVal.Unix()), float64(maxVal.Unix()), true
+	switch epoch {
+	case "":
+		return d
+	case "ms":
+		d.min, d.max = d.min*1e3, d.max*1e3+999
+	case "us", "µs":
+		d.min, d.max = d.min*1e6, d.max*1e6+999999
+	}
+	d.kind = "int"
+	return d
+}
+
+// histogramRange returns the smallest lower and the largest upper bound of the buckets.
+func histogramRange(opts string) (float64, float64, bool) {
+	minVal, maxVal := math.Inf(1), math.Inf(-1)
+	for _, opt := range strings.Split(opts, ";") {
+		if i := strings.LastIndex(opt, ":"); i >= 0 {
+			opt = opt[:i]
+		}
+		lo, hi := opt, opt
+		if i := strings.Index(opt, ".."); i >= 0 {
+			lo, hi = opt[:i], opt[i+2:]
+		}
+		l, err1 := strconv.ParseFloat(strings.TrimSpace(lo), 64)
+		h, err2 := strconv.ParseFloat(strings.TrimSpace(hi), 64)
+		if err1 != nil || err2 != nil {
+			return 0, 0, false
+		}
+		if lo == hi {
+			h++ // A single value, make the bound exclusive like for ranges.
+		}
+		minVal, maxVal = math.Min(minVal, l), math.Max(maxVal, h)
+	}
+	return minVal, maxVal, true
+}
+
+// Check compares the table definition with the schema of the table and returns all problems found: columns missing
+// from either side, NULLs generated for NOT NULL columns and generated values that don't fit the column type.
+func Check(tdef *config.TableDef, t *Table) []error {
+	var problems []error
+	configured := map[string]bool{}
+	for _, col := range tdef.Columns {
+		configured[col.Name] = true
+		c := t.Column(col.Name)
+		if c == nil {
+			problems = append(problems, fmt.Errorf("column %s doesn't exist", col.Name))
+			continue
+		}
+		if col.Nullable > 0 && !c.Nullable {
+			problems = append(problems, fmt.Errorf("column %s is NOT NULL, but Nullable is %g", col.Name, col.Nullable))
+		}
+		d, ok := generatorDomain(col.ColumnDef, tdef.TotalRecords)
+		if !ok {
+			continue
+		}
+		if err := checkDomain(*c, d); err != nil {
+			problems = append(problems, fmt.Errorf("column %s (%s): %s", col.Name, c.ColumnType, err))
+		}
+	}
+	for _, c := range t.Columns {
+		// Columns missing from config are copied when masking.
+		if tdef.Mask != nil || configured[c.Name] || c.Nullable || c.Default.Valid ||
+			strings.Contains(c.Extra, "auto_increment") || strings.Contains(strings.ToUpper(c.Extra), "GENERATED") {
+			continue
+		}
+		problems = append(problems, fmt.Errorf("column %s is NOT NULL without a default, but is missing from config", c.Name))
+	}
+	return problems
+}
+
+func checkDomain(c Column, d domain) error {
+	unsigned := strings.Contains(c.ColumnType, "unsigned")
+	switch c.DataType {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		if d.kind == "string" || d.kind == "datetime" || d.kind == "date" || d.kind == "time" {
+			return fmt.Errorf("%s generator can't be used for integers", d.kind)
+		}
+		minVal, maxVal := columnIntRange(c.DataType, unsigned)
+		return checkRange(d, minVal, maxVal)
+	case "decimal", "numeric":
+		if d.kind == "string" || d.kind == "datetime" || d.kind == "date" || d.kind == "time" {
+			return fmt.Errorf("%s generator can't be used for decimals", d.kind)
+		}
+		limit := math.Pow10(int(c.Precision.Int64 - c.Scale.Int64))
+		minVal := -limit
+		if unsigned {
+			minVal = 0
+		}
+		return checkRange(d, minVal, limit)
+	case "enum":
+		if d.values == nil {
+			return fmt.Errorf("only oneof generators can be used for enums")
+		}
+		members := map[string]bool{}
+		for _, m := range enumValues(c.ColumnType) {
+			members[m] = true
+		}
+		for _, v := range d.values {
+			if !members[v] {
+				return fmt.Errorf("%q is not a member of the enum", v)
+			}
+		}
+	case "char", "varchar", "binary", "varbinary", "tinytext", "text", "mediumtext", "longtext",
+		"tinyblob", "blob", "mediumblob", "longblob":
+		if c.MaxLength.Valid && int64(d.maxLen) > c.MaxLength.Int64 {
+			return fmt.Errorf("generated strings can be %d characters long, but at most %d fit",
+				d.maxLen, c.MaxLength.Int64)
+		}
+	case "timestamp":
+		if d.kind == "datetime" && d.hasRange &&
+			(d.min < float64(minTimestamp.Unix()) || d.max > float64(maxTimestamp.Unix())) {
+			return fmt.Errorf("generated datetimes are outside of TIMESTAMP range [%s, %s]",
+				minTimestamp.Format("2006-01-02 15:04:05"), maxTimestamp.Format("2006-01-02 15:04:05"))
+		}
+	}
+	return nil
+}
+
+func checkRange(d domain, minVal, maxVal float64) error {
+	inRange := func(v float64) bool {
+		return v >= minVal && v <= maxVal
+	}
+	for _, raw := range d.values {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		if !inRange(v) {
+			return fmt.Errorf("%q is out of range", raw)
+		}
+	}
+	// Unbounded sides, e.g. the upper one of float/exp, can't be checked. Such generators need sensible parameters.
+	bounded := func(v float64) bool {
+		return inRange(v) || math.IsInf(v, 0)
+	}
+	if d.hasRange && (!bounded(d.min) || !bounded(d.max)) {
+		return fmt.Errorf("generated values [%g, %g] don't fit into the column", d.min, d.max)
+	}
+	return nil
+}
+
+func columnIntRange(dataType string, unsigned bool) (float64, float64) {
+	if dataType == "bigint" {
+		if unsigned {
+			return 0, math.MaxUint64
+		}
+		return math.MinInt64, math.MaxInt64
+	}
+	r := intRanges[dataType]
+	if unsigned {
+		return 0, float64(2*r[1] + 1)
+	}
+	return float64(r[0]), float64(r[1])
+}
@@ -0,0 +1,102 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	table := &Table{
+		Name: "account_walletdeposit",
+		Columns: []Column{
+			{Name: "id", DataType: "int", ColumnType: "int unsigned", Key: "PRI", Extra: "auto_increment"},
+			{Name: "kind", DataType: "tinyint", ColumnType: "tinyint"},
+			{Name: "txid", DataType: "varchar", ColumnType: "varchar(20)", MaxLength: nullInt(20)},
+			{Name: "amount", DataType: "decimal", ColumnType: "decimal(5,2) unsigned", Precision: nullInt(5), Scale: nullInt(2)},
+			{Name: "status", DataType: "enum", ColumnType: "enum('new','done')"},
+			{Name: "created", DataType: "timestamp", ColumnType: "timestamp"},
+			{Name: "note", DataType: "text", ColumnType: "text", Nullable: true, MaxLength: nullInt(65535)},
+			{Name: "fee", DataType: "int", ColumnType: "int", Default: nullStr("0")},
+			{Name: "user_id", DataType: "int", ColumnType: "int"},
+		},
+	}
+
+	t.Run("matching config", func(t *testing.T) {
+		tdef := &config.TableDef{
+			TotalRecords: 1000,
+			Columns: config.Columns{
+				{Name: "id", ColumnDef: config.ColumnDef{Type: "int/incremental-uniform", First: "1", MinVal: "1", MaxVal: "5"}},
+				{Name: "kind", ColumnDef: config.ColumnDef{Type: "int/oneof", OneOf: "-1;0;127:10"}},
+				{Name: "txid", ColumnDef: config.ColumnDef{Type: "string", Length: 20}},
+				{Name: "amount", ColumnDef: config.ColumnDef{Type: "float", MinVal: "0", MaxVal: "999.99"}},
+				{Name: "status", ColumnDef: config.ColumnDef{Type: "string/oneof", OneOf: "new:10;done"}},
+				{Name: "created", ColumnDef: config.ColumnDef{Type: "datetime/uniform", MinVal: "2011-08-15 18:18:18", MaxVal: "2021-12-01 21:54:35"}},
+				{Name: "note", ColumnDef: config.ColumnDef{Type: "string/text", Length: 500, Nullable: 0.5}},
+				{Name: "user_id", ColumnDef: config.ColumnDef{Type: "int", MinVal: "1", MaxVal: "2000"}},
+			},
+		}
+		assert.Empty(t, Check(tdef, table))
+	})
+
+	t.Run("mismatched config", func(t *testing.T) {
+		tdef := &config.TableDef{
+			TotalRecords: 1000,
+			Columns: config.Columns{
+				{Name: "kind", ColumnDef: config.ColumnDef{Type: "int", MinVal: "0", MaxVal: "1000"}},
+				{Name: "txid", ColumnDef: config.ColumnDef{Type: "string/uuid"}},
+				{Name: "amount", ColumnDef: config.ColumnDef{Type: "float/oneof", OneOf: "-1.5;2"}},
+				{Name: "status", ColumnDef: config.ColumnDef{Type: "string/oneof", OneOf: "new;pending"}},
+				{Name: "created", ColumnDef: config.ColumnDef{Type: "datetime/uniform", MaxVal: "2021-12-01 21:54:35"}},
+				{Name: "note", ColumnDef: config.ColumnDef{Type: "string/text", Length: 500, Format: "'%v'"}},
+				{Name: "fee", ColumnDef: config.ColumnDef{Type: "int", MinVal: "0", MaxVal: "10", Nullable: 0.1}},
+				{Name: "renamed", ColumnDef: config.ColumnDef{Type: "int", MinVal: "0", MaxVal: "10"}},
+			},
+		}
+		problems := Check(tdef, table)
+		msgs := make([]string, 0, len(problems))
+		for _, p := range problems {
+			msgs = append(msgs, p.Error())
+		}
+		assert.Equal(t, []string{
+			"column kind (tinyint): generated values [0, 999] don't fit into the column",
+			"column txid (varchar(20)): generated strings can be 36 characters long, but at most 20 fit",
+			"column amount (decimal(5,2) unsigned): \"-1.5\" is out of range",
+			"column status (enum('new','done')): \"pending\" is not a member of the enum",
+			"column created (timestamp): generated datetimes are outside of TIMESTAMP range [1970-01-01 00:00:01, 2038-01-19 03:14:07]",
+			"column fee is NOT NULL, but Nullable is 0.1",
+			"column renamed doesn't exist",
+			"column user_id is NOT NULL without a default, but is missing from config",
+		}, msgs)
+	})
+	t.Run("unbounded generators", func(t *testing.T) {
+		prices := &Table{Name: "prices", Columns: []Column{
+			{Name: "price", DataType: "decimal", ColumnType: "decimal(10,2) unsigned", Precision: nullInt(10), Scale: nullInt(2)},
+			{Name: "qty", DataType: "int", ColumnType: "int unsigned"},
+			{Name: "walk", DataType: "decimal", ColumnType: "decimal(10,2)", Precision: nullInt(10), Scale: nullInt(2)},
+		}}
+		tdef := &config.TableDef{TotalRecords: 100, Columns: config.Columns{
+			{Name: "price", ColumnDef: config.ColumnDef{Type: "float/normal", MinVal: "90", MaxVal: "110"}},
+			{Name: "qty", ColumnDef: config.ColumnDef{Type: "float/exp", MinVal: "1", MaxVal: "20"}},
+			{Name: "walk", ColumnDef: config.ColumnDef{Type: "float/randomwalk", First: "100"}},
+		}}
+		assert.Empty(t, Check(tdef, prices))
+		tdef.Columns[1].MinVal = "-5"
+		assert.Len(t, Check(tdef, prices), 1, "the lower bound of float/exp is still checked")
+	})
+	t.Run("incremental datetimes", func(t *testing.T) {
+		events := &Table{Name: "events", Columns: []Column{{Name: "created", DataType: "timestamp", ColumnType: "timestamp"}}}
+		created := config.ColumnDef{Type: "datetime/incremental", First: "2038-01-01 00:00:00", MaxVal: "1h"}
+		tdef := &config.TableDef{TotalRecords: 100, Columns: config.Columns{{Name: "created", ColumnDef: created}}}
+		assert.Empty(t, Check(tdef, events))
+		tdef.TotalRecords = 1000
+		assert.Len(t, Check(tdef, events), 1, "the last of 1000 hourly steps is past 2038-01-19")
+
+		events.Columns[0] = Column{Name: "created", DataType: "int", ColumnType: "int"}
+		tdef.Columns[0].Epoch = "s"
+		assert.Len(t, Check(tdef, events), 1, "epoch seconds past 2038-01-19 don't fit into INT")
+		tdef.Columns[0].Epoch = ""
+		assert.EqualError(t, Check(tdef, events)[0], "column created (int): datetime generator can't be used for integers")
+	})
+}
@@ -0,0 +1,138 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bitstonks/syndi/internal/config"
+)
+
+// maxVarcharLength is the longest string stored as VARCHAR in created tables, longer ones are TEXT.
+const maxVarcharLength = 255
+
+// Dialect holds what differs between databases when creating tables.
+type Dialect struct {
+	Name  string
+	quote string            // Character quoting identifiers.
+	types map[string]string // SQL types for generator kinds, varchar and char take the length.
+}
+
+var (
+	MySQL = &Dialect{
+		Name:  "mysql",
+		quote: "`",
+		types: map[string]string{
+			"bool":     "TINYINT(1)",
+			"int":      "BIGINT",
+			"float":    "DOUBLE",
+			"datetime": "DATETIME",
+			"date":     "DATE",
+			"time":     "TIME",
+			"char":     "CHAR(%d)",
+			"varchar":  "VARCHAR(%d)",
+			"text":     "TEXT",
+		},
+	}
+	PostgreSQL = &Dialect{
+		Name:  "postgres",
+		quote: `"`,
+		types: map[string]string{
+			"bool":     "SMALLINT", // Generators produce 0 and 1, which BOOLEAN doesn't accept.
+			"int":      "BIGINT",
+			"float":    "DOUBLE PRECISION",
+			"datetime": "TIMESTAMP",
+			"date":     "DATE",
+			"time":     "TIME",
+			"char":     "CHAR(%d)",
+			"varchar":  "VARCHAR(%d)",
+			"text":     "TEXT",
+		},
+	}
+)
+
+var dialects = map[string]*Dialect{
+	MySQL.Name:      MySQL,
+	PostgreSQL.Name: PostgreSQL,
+}
+
+// GetDialect returns the dialect with the given name.
+func GetDialect(name string) (*Dialect, error) {
+	d, ok := dialects[name]
+	if !ok {
+		names := make([]string, 0, len(dialects))
+		for n := range dialects {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown dialect %q, use one of: %s", name, strings.Join(names, ", "))
+	}
+	return d, nil
+}
+
+// QuoteIdent quotes an identifier like a table or a column name.
+func (d *Dialect) QuoteIdent(name string) string {
+	return d.quote + strings.ReplaceAll(name, d.quote, d.quote+d.quote) + d.quote
+}
+
+// ColumnType returns SQLType of the column or infers the type from the generator otherwise.
+func (d *Dialect) ColumnType(def config.ColumnDef) (string, error) {
+	if def.SQLType != "" {
+		return def.SQLType, nil
+	}
+	kind := def.Type
+	if i := strings.Index(kind, "/"); i >= 0 {
+		kind = kind[:i]
+	}
+	switch kind {
+	case "bool", "int", "float":
+		return d.types[kind], nil
+	case "datetime", "date", "time":
+		if def.Epoch != "" {
+			return d.types["int"], nil
+		}
+		if def.Precision > 0 && kind != "date" {
+			return fmt.Sprintf("%s(%d)", d.types[kind], def.Precision), nil
+		}
+		return d.types[kind], nil
+	case "string":
+		if def.Format != "" {
+			break
+		}
+		if def.Type == "string/uuid" {
+			return fmt.Sprintf(d.types["char"], 36), nil
+		}
+		dom, ok := generatorDomain(def, 0)
+		if !ok || dom.maxLen <= 0 || dom.maxLen > maxVarcharLength {
+			return d.types["text"], nil
+		}
+		return fmt.Sprintf(d.types["varchar"], dom.maxLen), nil
+	}
+	return "", fmt.Errorf("can't infer the SQL type of %s generator, set SQLType", def.Type)
+}
+
+// CreateTable returns the CREATE TABLE statement for the table definition. The first column with an incremental
+// integer generator becomes the primary key. Incremental datetimes can repeat, so they can't be keys.
+func (d *Dialect) CreateTable(tdef *config.TableDef) (string, error) {
+	lines := make([]string, 0, len(tdef.Columns)+1)
+	pk := ""
+	for _, col := range tdef.Columns {
+		typ, err := d.ColumnType(col.ColumnDef)
+		if err != nil {
+			return "", fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		null := "NOT NULL"
+		if col.Nullable > 0 {
+			null = "NULL"
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s %s", d.QuoteIdent(col.Name), typ, null))
+		if pk == "" && col.Type == "int/incremental-uniform" && col.Nullable == 0 {
+			pk = col.Name
+		}
+	}
+	if pk != "" {
+		lines = append(lines, fmt.Sprintf("  PRIMARY KEY (%s)", d.QuoteIdent(pk)))
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n)", d.QuoteIdent(tdef.TableName),
+		strings.Join(lines, ",\n")), nil
+}
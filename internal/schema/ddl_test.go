@@ -0,0 +1,85 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTable(t *testing.T) {
+	tdef := &config.TableDef{
+		TableName: "user",
+		Columns: config.Columns{
+			{Name: "id", ColumnDef: config.ColumnDef{Type: "int/incremental-uniform", MinVal: "1", MaxVal: "2"}},
+			{Name: "uuid", ColumnDef: config.ColumnDef{Type: "string/uuid"}},
+			{Name: "name", ColumnDef: config.ColumnDef{Type: "string/rand", Lengths: "3..20:1;20..40:1"}},
+			{Name: "bio", ColumnDef: config.ColumnDef{Type: "string/text", Length: 1000, Nullable: 0.3}},
+			{Name: "role", ColumnDef: config.ColumnDef{Type: "string/oneof", OneOf: "user:10;admin"}},
+			{Name: "active", ColumnDef: config.ColumnDef{Type: "bool"}},
+			{Name: "balance", ColumnDef: config.ColumnDef{Type: "float/uniform", Format: "%.2f"}},
+			{Name: "created", ColumnDef: config.ColumnDef{Type: "datetime/uniform"}},
+			{Name: "country", ColumnDef: config.ColumnDef{Type: "oneof", OneOf: "'SI';'DE'", SQLType: "CHAR(2)"}},
+		},
+	}
+
+	ddl, err := MySQL.CreateTable(tdef)
+	assert.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS `user` (\n"+
+		"  `id` BIGINT NOT NULL,\n"+
+		"  `uuid` CHAR(36) NOT NULL,\n"+
+		"  `name` VARCHAR(39) NOT NULL,\n"+
+		"  `bio` TEXT NULL,\n"+
+		"  `role` VARCHAR(5) NOT NULL,\n"+
+		"  `active` TINYINT(1) NOT NULL,\n"+
+		"  `balance` DOUBLE NOT NULL,\n"+
+		"  `created` DATETIME NOT NULL,\n"+
+		"  `country` CHAR(2) NOT NULL,\n"+
+		"  PRIMARY KEY (`id`)\n"+
+		")", ddl)
+
+	ddl, err = PostgreSQL.CreateTable(tdef)
+	assert.NoError(t, err)
+	assert.Contains(t, ddl, `CREATE TABLE IF NOT EXISTS "user" (`)
+	assert.Contains(t, ddl, `"active" SMALLINT NOT NULL`)
+	assert.Contains(t, ddl, `"created" TIMESTAMP NOT NULL`)
+
+	tdef.Columns[8].SQLType = ""
+	_, err = MySQL.CreateTable(tdef)
+	assert.EqualError(t, err, "column country: can't infer the SQL type of oneof generator, set SQLType")
+
+	_, err = GetDialect("oracle")
+	assert.EqualError(t, err, `unknown dialect "oracle", use one of: mysql, postgres`)
+}
+
+func TestCreateTableIncrementalDatetimeIsNoKey(t *testing.T) {
+	tdef := &config.TableDef{
+		TableName: "events",
+		Columns: config.Columns{
+			{Name: "created", ColumnDef: config.ColumnDef{Type: "datetime/incremental", First: "2021-01-01 00:00:00"}},
+			{Name: "day", ColumnDef: config.ColumnDef{Type: "date/incremental", First: "2021-01-01"}},
+			{Name: "id", ColumnDef: config.ColumnDef{Type: "int/incremental-uniform", MinVal: "1", MaxVal: "2"}},
+		},
+	}
+	ddl, err := MySQL.CreateTable(tdef)
+	assert.NoError(t, err)
+	assert.Contains(t, ddl, "  PRIMARY KEY (`id`)\n)")
+
+	tdef.Columns = tdef.Columns[:2]
+	ddl, err = MySQL.CreateTable(tdef)
+	assert.NoError(t, err)
+	assert.NotContains(t, ddl, "PRIMARY KEY")
+}
+
+func TestColumnTypeTimes(t *testing.T) {
+	for def, want := range map[config.ColumnDef]string{
+		{Type: "date/uniform"}:                   "DATE",
+		{Type: "time/uniform", Precision: 3}:     "TIME(3)",
+		{Type: "datetime/uniform", Precision: 6}: "DATETIME(6)",
+		{Type: "datetime/uniform", Epoch: "ms"}:  "BIGINT",
+	} {
+		typ, err := MySQL.ColumnType(def)
+		assert.NoError(t, err)
+		assert.Equal(t, want, typ, def.Type)
+	}
+}
@@ -0,0 +1,71 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitstonks/syndi/internal/config"
+)
+
+// InsertClauses returns the beginning of INSERT statements up to the table name (e.g. `INSERT IGNORE INTO`) and their
+// end after the values, so that rows conflicting with existing ones are handled as configured.
+func (d *Dialect) InsertClauses(c config.ConflictDef, columns []string) (string, string, error) {
+	if c.Action == "" || c.Action == config.ConflictError {
+		return "INSERT INTO", "", nil
+	}
+	if d == MySQL {
+		switch c.Action {
+		case config.ConflictIgnore:
+			return "INSERT IGNORE INTO", "", nil
+		case config.ConflictReplace:
+			return "REPLACE INTO", "", nil
+		}
+	} else if c.Action == config.ConflictIgnore {
+		return "INSERT INTO", " ON CONFLICT DO NOTHING", nil
+	}
+
+	update := c.Update
+	if len(update) == 0 || c.Action == config.ConflictReplace {
+		update = nil
+		for _, col := range columns {
+			if !contains(c.Key, col) {
+				update = append(update, col)
+			}
+		}
+	}
+	sets := make([]string, 0, len(update))
+	for _, col := range update {
+		if !contains(columns, col) {
+			return "", "", fmt.Errorf("column %s updated on conflict is not inserted", col)
+		}
+		if d == MySQL {
+			sets = append(sets, fmt.Sprintf("%s=VALUES(%s)", d.QuoteIdent(col), d.QuoteIdent(col)))
+		} else {
+			sets = append(sets, fmt.Sprintf("%s=EXCLUDED.%s", d.QuoteIdent(col), d.QuoteIdent(col)))
+		}
+	}
+	if len(sets) == 0 {
+		return "", "", fmt.Errorf("no columns to update on conflict")
+	}
+	if d == MySQL {
+		return "INSERT INTO", " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ","), nil
+	}
+	if len(c.Key) == 0 {
+		return "", "", fmt.Errorf("%s needs the Key of OnConflict to %s rows", d.Name, c.Action)
+	}
+	key := make([]string, 0, len(c.Key))
+	for _, col := range c.Key {
+		key = append(key, d.QuoteIdent(col))
+	}
+	return "INSERT INTO", fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(key, ","),
+		strings.Join(sets, ",")), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertClauses(t *testing.T) {
+	cols := []string{"id", "status", "amount"}
+	for _, tc := range []struct {
+		dialect *Dialect
+		def     config.ConflictDef
+		verb    string
+		suffix  string
+	}{
+		{MySQL, config.ConflictDef{}, "INSERT INTO", ""},
+		{MySQL, config.ConflictDef{Action: "error"}, "INSERT INTO", ""},
+		{MySQL, config.ConflictDef{Action: "ignore"}, "INSERT IGNORE INTO", ""},
+		{MySQL, config.ConflictDef{Action: "replace"}, "REPLACE INTO", ""},
+		{MySQL, config.ConflictDef{Action: "update", Update: []string{"status"}}, "INSERT INTO",
+			" ON DUPLICATE KEY UPDATE `status`=VALUES(`status`)"},
+		{MySQL, config.ConflictDef{Action: "update", Key: []string{"id"}}, "INSERT INTO",
+			" ON DUPLICATE KEY UPDATE `status`=VALUES(`status`),`amount`=VALUES(`amount`)"},
+		{PostgreSQL, config.ConflictDef{Action: "ignore"}, "INSERT INTO", " ON CONFLICT DO NOTHING"},
+		{PostgreSQL, config.ConflictDef{Action: "update", Key: []string{"id"}, Update: []string{"amount"}},
+			"INSERT INTO", ` ON CONFLICT ("id") DO UPDATE SET "amount"=EXCLUDED."amount"`},
+		{PostgreSQL, config.ConflictDef{Action: "replace", Key: []string{"id"}, Update: []string{"amount"}},
+			"INSERT INTO", ` ON CONFLICT ("id") DO UPDATE SET "status"=EXCLUDED."status","amount"=EXCLUDED."amount"`},
+	} {
+		verb, suffix, err := tc.dialect.InsertClauses(tc.def, cols)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.verb, verb, "%s %+v", tc.dialect.Name, tc.def)
+		assert.Equal(t, tc.suffix, suffix, "%s %+v", tc.dialect.Name, tc.def)
+	}
+
+	_, _, err := PostgreSQL.InsertClauses(config.ConflictDef{Action: "update"}, cols)
+	assert.EqualError(t, err, "postgres needs the Key of OnConflict to update rows")
+	_, _, err = MySQL.InsertClauses(config.ConflictDef{Action: "update", Update: []string{"note"}}, cols)
+	assert.EqualError(t, err, "column note updated on conflict is not inserted")
+	_, _, err = MySQL.InsertClauses(config.ConflictDef{Action: "update", Key: []string{"id"}}, []string{"id"})
+	assert.EqualError(t, err, "no columns to update on conflict")
+}
@@ -0,0 +1,328 @@
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/generators"
+)
+
+// ProfileOptions limit how much of the scanned data ends up in the generated config. Apart from aggregated statistics
+// (NULL fraction, minimums, maximums and histograms) only values of low-cardinality columns that are at least MinFreq
+// frequent and occur at least MinCount times are written out.
+type ProfileOptions struct {
+	Sample   int     // Maximum number of rows to scan (randomly sampled), zero scans the whole table.
+	TopK     int     // Columns with at most this many distinct values are profiled as weighted OneOf.
+	MinFreq  float64 // Values less frequent than this are never written to the config.
+	MinCount int     // Values occurring fewer times than this are never written to the config, even in small tables.
+	Buckets  int     // Number of histogram buckets for numbers and string lengths.
+}
+
+// DefaultProfileOptions are reasonable settings for most tables.
+var DefaultProfileOptions = ProfileOptions{
+	Sample:   100000,
+	TopK:     20,
+	MinFreq:  0.01,
+	MinCount: 10,
+	Buckets:  10,
+}
+
+// weightScale is the sum of weights of profiled OneOf options and histograms. Weights are relative frequencies scaled
+// to this value rather than counts, so the size of the table isn't leaked.
+const weightScale = 1000
+
+// Profile scans (a sample of) the table and creates a TableDef with generators approximating the distributions of
+// values in each column.
+func Profile(db *sql.DB, t *Table, totalRecords, batchSize int, opts ProfileOptions) (*config.TableDef, error) {
+	cols := make([]string, 0, len(t.Columns))
+	profiles := make([]*columnProfile, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		cols = append(cols, QuoteIdent(c.Name))
+		profiles = append(profiles, newColumnProfile(c, opts))
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(cols, ","), QuoteIdent(t.Name))
+	if opts.Sample > 0 {
+		var count int
+		err := db.QueryRow("SELECT COUNT(*) FROM " + QuoteIdent(t.Name)).Scan(&count)
+		if err != nil {
+			return nil, err
+		}
+		if count > opts.Sample {
+			query += fmt.Sprintf(" WHERE RAND() < %g", float64(opts.Sample)/float64(count))
+		}
+	}
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, p := range profiles {
+			p.add(values[i])
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tdef := &config.TableDef{
+		TableName:    t.Name,
+		TotalRecords: totalRecords,
+		BatchSize:    batchSize,
+	}
+	for _, p := range profiles {
+		tdef.Columns = append(tdef.Columns, config.Column{Name: p.col.Name, ColumnDef: p.columnDef(t)})
+	}
+	return tdef, nil
+}
+
+// columnProfile collects statistics of the values in a single column.
+type columnProfile struct {
+	col      Column
+	opts     ProfileOptions
+	category string // int, float, datetime, time, string or empty if the values aren't profiled
+	rows     int
+	nulls    int
+	counts   map[string]int // Counts of distinct values, nil once there are more than TopK of them.
+	numbers  []float64      // Numbers, unix timestamps, seconds since midnight or string lengths.
+	spaces   int            // Number of strings containing spaces.
+}
+
+func newColumnProfile(c Column, opts ProfileOptions) *columnProfile {
+	category := "string"
+	switch c.DataType {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "year":
+		category = "int"
+	case "decimal", "numeric", "float", "double", "real":
+		category = "float"
+	case "date", "datetime", "timestamp":
+		category = "datetime"
+	case "time":
+		category = "time"
+	case "bit", "json":
+		// Bits are scanned as raw bytes and JSON documents can't be written as OneOf options, so only the NULL
+		// fraction is profiled and the generator is suggested like with `syndi init`.
+		category = ""
+	}
+	p := &columnProfile{
+		col:      c,
+		opts:     opts,
+		category: category,
+	}
+	if category != "" {
+		p.counts = map[string]int{}
+	}
+	return p
+}
+
+func (p *columnProfile) add(v interface{}) {
+	p.rows++
+	if v == nil {
+		p.nulls++
+		return
+	}
+	var raw string
+	switch val := v.(type) {
+	case time.Time:
+		if p.col.DataType == "date" {
+			raw = val.UTC().Format("2006-01-02")
+		} else {
+			raw = val.UTC().Format("2006-01-02 15:04:05")
+		}
+		p.numbers = append(p.numbers, float64(val.Unix()))
+	case []byte:
+		raw = string(val)
+	default:
+		raw = fmt.Sprint(val)
+	}
+	switch p.category {
+	case "int", "float":
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			p.numbers = append(p.numbers, n)
+		}
+	case "time":
+		// Only times of day can be generated, not the whole TIME range of -838:59:59 to 838:59:59.
+		clock := raw
+		if i := strings.Index(clock, "."); i >= 0 {
+			clock = clock[:i]
+		}
+		if t, err := time.Parse(generators.TimeLayout, clock); err == nil {
+			p.numbers = append(p.numbers, float64(t.Hour()*3600+t.Minute()*60+t.Second()))
+		}
+	case "string":
+		p.numbers = append(p.numbers, float64(utf8.RuneCountInString(raw)))
+		if strings.Contains(raw, " ") {
+			p.spaces++
+		}
+	}
+	if p.counts != nil {
+		p.counts[raw]++
+		if len(p.counts) > p.opts.TopK {
+			p.counts = nil
+		}
+	}
+}
+
+func (p *columnProfile) columnDef(t *Table) config.ColumnDef {
+	c := p.col
+	if c.Key == "PRI" || c.Key == "UNI" || c.Ref != nil || p.rows == p.nulls {
+		// Keys have to stay unique or valid, and there is nothing to learn from empty columns.
+		def := SuggestColumnDef(t, c)
+		if p.rows > 0 && p.rows == p.nulls {
+			def.Nullable = 1
+		}
+		return def
+	}
+	def := p.distribution()
+	if p.nulls > 0 {
+		def.Nullable = math.Round(float64(p.nulls)/float64(p.rows)*10000) / 10000
+	}
+	return def
+}
+
+func (p *columnProfile) distribution() config.ColumnDef {
+	nonNull := float64(p.rows - p.nulls)
+	if p.counts != nil {
+		if opts := p.frequentValues(nonNull); opts != "" {
+			genType := p.category + "/oneof"
+			if p.category == "time" {
+				genType = "datetime/oneof"
+			}
+			return config.ColumnDef{Type: genType, OneOf: opts}
+		}
+	}
+	if len(p.numbers) == 0 || p.category == "" || p.col.DataType == "enum" || p.col.DataType == "set" {
+		return SuggestColumnDef(&Table{}, p.col)
+	}
+	minVal, maxVal := p.numbers[0], p.numbers[0]
+	for _, n := range p.numbers {
+		minVal = math.Min(minVal, n)
+		maxVal = math.Max(maxVal, n)
+	}
+
+	switch p.category {
+	case "int":
+		return config.ColumnDef{Type: "int/histogram", OneOf: p.histogram(minVal, maxVal+1, true)}
+	case "float":
+		def := config.ColumnDef{Type: "float/histogram", OneOf: p.histogram(minVal, maxVal, false)}
+		if p.col.Scale.Valid && (p.col.DataType == "decimal" || p.col.DataType == "numeric") {
+			def.Format = fmt.Sprintf("%%.%df", p.col.Scale.Int64)
+		}
+		return def
+	case "datetime":
+		first, last := time.Unix(int64(minVal), 0).UTC(), time.Unix(int64(maxVal), 0).UTC()
+		if p.col.DataType == "date" {
+			return config.ColumnDef{
+				Type:   "date/uniform",
+				MinVal: first.Format("2006-01-02"),
+				MaxVal: last.AddDate(0, 0, 1).Format("2006-01-02"),
+			}
+		}
+		return config.ColumnDef{
+			Type:   "datetime/uniform",
+			MinVal: first.Format("2006-01-02 15:04:05"),
+			MaxVal: last.Add(time.Second).Format("2006-01-02 15:04:05"),
+		}
+	case "time":
+		midnight := time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)
+		def := config.ColumnDef{
+			Type:   "time/uniform",
+			MinVal: midnight.Add(time.Duration(minVal) * time.Second).Format(generators.TimeLayout),
+		}
+		// Up to the end of the day MaxVal is left empty, 24:00:00 can't be parsed.
+		if maxVal < 24*3600-1 {
+			def.MaxVal = midnight.Add(time.Duration(maxVal+1) * time.Second).Format(generators.TimeLayout)
+		}
+		return def
+	default:
+		gen := "string/rand"
+		if float64(p.spaces) > nonNull/2 {
+			gen = "string/text"
+		}
+		return config.ColumnDef{Type: gen, Lengths: p.histogram(minVal, maxVal+1, true)}
+	}
+}
+
+// frequentValues formats values at least MinFreq frequent and occurring at least MinCount times as OneOf options, the
+// most frequent first.
+func (p *columnProfile) frequentValues(nonNull float64) string {
+	type valueCount struct {
+		value string
+		count int
+	}
+	var frequent []valueCount
+	for v, n := range p.counts {
+		// Options are separated by semicolons, so such values can't be written out.
+		if float64(n)/nonNull >= p.opts.MinFreq && n >= p.opts.MinCount && !strings.Contains(v, ";") {
+			frequent = append(frequent, valueCount{v, n})
+		}
+	}
+	sort.Slice(frequent, func(i, j int) bool {
+		if frequent[i].count != frequent[j].count {
+			return frequent[i].count > frequent[j].count
+		}
+		return frequent[i].value < frequent[j].value
+	})
+	opts := make([]string, 0, len(frequent))
+	for _, vc := range frequent {
+		opts = append(opts, fmt.Sprintf("%s:%d", vc.value, scaledWeight(vc.count, nonNull)))
+	}
+	return strings.Join(opts, ";")
+}
+
+// histogram formats an equal-width histogram of the collected numbers over [lo, hi] as OneOf options. Integer
+// histograms have integer bucket bounds and a half-open range [lo, hi).
+func (p *columnProfile) histogram(lo, hi float64, integer bool) string {
+	n := p.opts.Buckets
+	if n < 1 {
+		n = 1
+	}
+	width := (hi - lo) / float64(n)
+	if integer {
+		width = math.Ceil(width)
+	}
+	if width <= 0 {
+		return strconv.FormatFloat(lo, 'f', -1, 64)
+	}
+	counts := make([]int, n)
+	for _, v := range p.numbers {
+		i := int((v - lo) / width)
+		if i >= n {
+			i = n - 1
+		}
+		counts[i]++
+	}
+	var opts []string
+	for i, count := range counts {
+		if count == 0 {
+			continue
+		}
+		bucketLo := lo + float64(i)*width
+		bucketHi := math.Min(bucketLo+width, hi)
+		opts = append(opts, fmt.Sprintf("%s..%s:%d",
+			strconv.FormatFloat(bucketLo, 'g', -1, 64),
+			strconv.FormatFloat(bucketHi, 'g', -1, 64),
+			scaledWeight(count, float64(len(p.numbers)))))
+	}
+	return strings.Join(opts, ";")
+}
+
+func scaledWeight(count int, total float64) int {
+	return int(math.Max(1, math.Round(float64(count)/total*weightScale)))
+}
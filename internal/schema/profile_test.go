@@ -0,0 +1,113 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/generators"
+	"github.com/stretchr/testify/assert"
+)
+
+// profileColumns feeds the rows into profiles of the table columns and returns the resulting TableDef.
+func profileColumns(t *Table, rows [][]interface{}, opts ProfileOptions) *config.TableDef {
+	tdef := &config.TableDef{TableName: t.Name, TotalRecords: 1000, BatchSize: 500}
+	for i, c := range t.Columns {
+		p := newColumnProfile(c, opts)
+		for _, row := range rows {
+			p.add(row[i])
+		}
+		tdef.Columns = append(tdef.Columns, config.Column{Name: c.Name, ColumnDef: p.columnDef(t)})
+	}
+	return tdef
+}
+
+func TestProfile(t *testing.T) {
+	created := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	var rows [][]interface{}
+	for i := 0; i < 100; i++ {
+		var address, note interface{}
+		if i%4 != 0 {
+			address = []byte(strings.Repeat("x", 26+i%10))
+		}
+		if i%2 == 0 {
+			note = []byte("some note " + strings.Repeat("y", i%5))
+		}
+		status := "done"
+		if i%10 == 0 {
+			status = "new"
+		}
+		if i == 99 {
+			status = "it's: odd" // Too rare to be written out.
+		}
+		rows = append(rows, []interface{}{
+			int64(i + 1), int64(i%7 + 1), int64(1), []byte(fmt.Sprintf("tx%d", i)), address,
+			[]byte(fmt.Sprintf("%d.5", i)), []byte(status), int64(i % 2), int64(i * 10), note,
+			created.Add(time.Duration(i) * time.Hour), created,
+		})
+	}
+	tdef := profileColumns(walletDeposit, rows, ProfileOptions{TopK: 5, MinFreq: 0.05, Buckets: 4})
+
+	expected := map[string]config.ColumnDef{
+		"currency_id": {Type: "int/oneof", OneOf: "1;2;5", Ref: "currency.id"},
+		"address":     {Type: "string/rand", Lengths: "26..29:267;29..32:333;32..35:267;35..36:133", Nullable: 0.25},
+		"amount":      {Type: "float/histogram", OneOf: "0.5..25.25:250;25.25..50:250;50..74.75:250;74.75..99.5:250", Format: "%.8f"},
+		"status":      {Type: "string/oneof", OneOf: "done:890;new:100"},
+		"instant":     {Type: "int/oneof", OneOf: "0:500;1:500"},
+		"priority":    {Type: "int/histogram", OneOf: "0..248:250;248..496:250;496..744:250;744..991:250"},
+		"note":        {Type: "string/oneof", OneOf: "some note :200;some note y:200;some note yy:200;some note yyy:200;some note yyyy:200", Nullable: 0.5},
+		"created":     {Type: "datetime/uniform", MinVal: "2021-03-01 12:00:00", MaxVal: "2021-03-05 15:00:01"},
+		"day":         {Type: "datetime/oneof", OneOf: "2021-03-01:1000"},
+	}
+	for _, col := range tdef.Columns {
+		if def, ok := expected[col.Name]; ok {
+			assert.Equal(t, def, col.ColumnDef, col.Name)
+		}
+		gen, err := generators.GetGenerator(col.ColumnDef)
+		if assert.NoError(t, err, col.Name) {
+			assert.NotPanics(t, func() { gen.Next() }, col.Name)
+		}
+	}
+	assert.Empty(t, Check(tdef, walletDeposit))
+}
+
+func TestProfileOtherTypes(t *testing.T) {
+	shifts := &Table{Name: "shift", Columns: []Column{
+		{Name: "starts", DataType: "time", ColumnType: "time"},
+		{Name: "ends", DataType: "time", ColumnType: "time(3)"},
+		{Name: "active", DataType: "bit", ColumnType: "bit(1)"},
+		{Name: "extra", DataType: "json", ColumnType: "json", Nullable: true},
+		{Name: "worker", DataType: "varchar", ColumnType: "varchar(20)", MaxLength: nullInt(20)},
+	}}
+	var rows [][]interface{}
+	for i := 0; i < 30; i++ {
+		var extra interface{}
+		if i%3 == 0 {
+			extra = []byte(fmt.Sprintf(`{"break": %d}`, i))
+		}
+		rows = append(rows, []interface{}{
+			[]byte(fmt.Sprintf("%02d:30:00", 6+i%12)), []byte(fmt.Sprintf("23:59:%02d.500", 30+i)),
+			[]byte{byte(i % 2)}, extra, []byte(fmt.Sprintf("worker%d", i%4)),
+		})
+	}
+	tdef := profileColumns(shifts, rows, ProfileOptions{TopK: 5, MinFreq: 0.05, MinCount: 10, Buckets: 2})
+
+	expected := config.Columns{
+		{Name: "starts", ColumnDef: config.ColumnDef{Type: "time/uniform", MinVal: "06:30:00", MaxVal: "17:30:01"}},
+		{Name: "ends", ColumnDef: config.ColumnDef{Type: "time/uniform", MinVal: "23:59:30"}},
+		{Name: "active", ColumnDef: config.ColumnDef{Type: "bool"}},
+		{Name: "extra", ColumnDef: config.ColumnDef{Type: "string/oneof", OneOf: "{}", Nullable: 0.6667}},
+		// Every worker occurs fewer than MinCount times, although more often than MinFreq.
+		{Name: "worker", ColumnDef: config.ColumnDef{Type: "string/rand", Lengths: "7..8:1000"}},
+	}
+	assert.Equal(t, expected, tdef.Columns)
+	for _, col := range tdef.Columns {
+		gen, err := generators.GetGenerator(col.ColumnDef)
+		if assert.NoError(t, err, col.Name) {
+			assert.NotPanics(t, func() { gen.Next() }, col.Name)
+		}
+	}
+	assert.Empty(t, Check(tdef, shifts))
+}
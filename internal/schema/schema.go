@@ -0,0 +1,157 @@
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// maxRefValues is the number of distinct values up to which a referenced column is listed in full.
+const maxRefValues = 100
+
+// Column describes a single table column as found in information_schema.COLUMNS.
+type Column struct {
+	Name       string
+	DataType   string // Plain type name like `int` or `varchar`.
+	ColumnType string // Full type like `int(10) unsigned` or `enum('a','b')`.
+	Nullable   bool
+	Default    sql.NullString
+	MaxLength  sql.NullInt64 // CHARACTER_MAXIMUM_LENGTH
+	Precision  sql.NullInt64 // NUMERIC_PRECISION
+	Scale      sql.NullInt64 // NUMERIC_SCALE
+	Key        string        // PRI, UNI, MUL or empty.
+	Extra      string        // E.g. `auto_increment`.
+	Ref        *Ref          // Set if the column is a foreign key.
+}
+
+// Ref is a column referenced by a foreign key together with the values found in it.
+type Ref struct {
+	Table  string
+	Column string
+	Values []string // All distinct values if there are at most maxRefValues of them.
+	Min    sql.NullString
+	Max    sql.NullString
+}
+
+// Table describes a table in the current database.
+type Table struct {
+	Name          string
+	AutoIncrement sql.NullInt64 // Next value of the auto_increment column.
+	Columns       []Column
+}
+
+// Column returns the column with the given name or nil.
+func (t *Table) Column(name string) *Column {
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// LoadTable reads the definition of the table from information_schema of the current database.
+func LoadTable(db *sql.DB, name string) (*Table, error) {
+	t := &Table{Name: name}
+	err := db.QueryRow(
+		"SELECT AUTO_INCREMENT FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?",
+		name,
+	).Scan(&t.AutoIncrement)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("table %s doesn't exist", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT COLUMN_NAME, DATA_TYPE, COLUMN_TYPE, IS_NULLABLE, COLUMN_DEFAULT,
+			CHARACTER_MAXIMUM_LENGTH, NUMERIC_PRECISION, NUMERIC_SCALE, COLUMN_KEY, EXTRA
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		c := Column{}
+		var nullable string
+		err = rows.Scan(&c.Name, &c.DataType, &c.ColumnType, &nullable, &c.Default,
+			&c.MaxLength, &c.Precision, &c.Scale, &c.Key, &c.Extra)
+		if err != nil {
+			return nil, err
+		}
+		c.DataType = strings.ToLower(c.DataType)
+		c.Nullable = nullable == "YES"
+		t.Columns = append(t.Columns, c)
+	}
+	return t, rows.Err()
+}
+
+// LoadRefs finds foreign keys of the table and loads the values of the referenced columns.
+func LoadRefs(db *sql.DB, t *Table) error {
+	rows, err := db.Query(`SELECT COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND REFERENCED_TABLE_NAME IS NOT NULL`, t.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	refs := map[string]*Ref{}
+	for rows.Next() {
+		var col string
+		ref := &Ref{}
+		if err = rows.Scan(&col, &ref.Table, &ref.Column); err != nil {
+			return err
+		}
+		refs[col] = ref
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for col, ref := range refs {
+		c := t.Column(col)
+		if c == nil {
+			continue
+		}
+		query := fmt.Sprintf("SELECT MIN(%[1]s), MAX(%[1]s), COUNT(DISTINCT %[1]s) FROM %[2]s",
+			QuoteIdent(ref.Column), QuoteIdent(ref.Table))
+		var distinct int
+		if err = db.QueryRow(query).Scan(&ref.Min, &ref.Max, &distinct); err != nil {
+			return err
+		}
+		if distinct > 0 && distinct <= maxRefValues {
+			ref.Values, err = loadValues(db, ref)
+			if err != nil {
+				return err
+			}
+		}
+		c.Ref = ref
+	}
+	return nil
+}
+
+func loadValues(db *sql.DB, ref *Ref) ([]string, error) {
+	query := fmt.Sprintf("SELECT DISTINCT %[1]s FROM %[2]s WHERE %[1]s IS NOT NULL ORDER BY 1",
+		QuoteIdent(ref.Column), QuoteIdent(ref.Table))
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var v string
+		if err = rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// QuoteIdent quotes a MySQL identifier like a table or a column name.
+func QuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
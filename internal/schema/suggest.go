@@ -0,0 +1,196 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/bitstonks/syndi/internal/config"
+)
+
+// defaultNullable is the fraction of NULLs suggested for NULLable columns.
+const defaultNullable = 0.1
+
+// maxTextLength caps the length of suggested string/text columns since TEXT columns can hold up to 4GB.
+const maxTextLength = 1000
+
+// intRanges holds [min, max] of signed integer types, unsigned ones are [0, 2*max+1].
+var intRanges = map[string][2]int64{
+	"tinyint":   {math.MinInt8, math.MaxInt8},
+	"smallint":  {math.MinInt16, math.MaxInt16},
+	"mediumint": {-1 << 23, 1<<23 - 1},
+	"int":       {math.MinInt32, math.MaxInt32},
+	"integer":   {math.MinInt32, math.MaxInt32},
+}
+
+// SuggestTableDef creates a starter TableDef with a generator for every column of the table.
+func SuggestTableDef(t *Table, totalRecords, batchSize int) *config.TableDef {
+	tdef := &config.TableDef{
+		TableName:    t.Name,
+		TotalRecords: totalRecords,
+		BatchSize:    batchSize,
+	}
+	for _, c := range t.Columns {
+		tdef.Columns = append(tdef.Columns, config.Column{Name: c.Name, ColumnDef: SuggestColumnDef(t, c)})
+	}
+	return tdef
+}
+
+// SuggestColumnDef picks a generator for the column based on its definition. Primary keys and unique integers become
+// incremental, foreign keys pick from the values of the referenced column and the rest is random within the limits of
+// the column type.
+func SuggestColumnDef(t *Table, c Column) config.ColumnDef {
+	def := suggestType(t, c)
+	if c.Nullable && c.Key != "PRI" {
+		def.Nullable = defaultNullable
+	}
+	return def
+}
+
+func suggestType(t *Table, c Column) config.ColumnDef {
+	if c.Ref != nil {
+		return suggestRef(c)
+	}
+	unique := c.Key == "PRI" || c.Key == "UNI"
+	unsigned := strings.Contains(c.ColumnType, "unsigned")
+
+	switch c.DataType {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		if c.ColumnType == "tinyint(1)" {
+			return config.ColumnDef{Type: "bool"}
+		}
+		if unique {
+			first := "1"
+			if strings.Contains(c.Extra, "auto_increment") && t.AutoIncrement.Valid {
+				first = strconv.FormatInt(t.AutoIncrement.Int64, 10)
+			}
+			return config.ColumnDef{Type: "int/incremental-uniform", First: first, MinVal: "1", MaxVal: "2"}
+		}
+		minVal, maxVal := intRange(c.DataType, unsigned)
+		return config.ColumnDef{Type: "int/uniform", MinVal: minVal, MaxVal: maxVal}
+	case "decimal", "numeric":
+		precision, scale := int64(10), int64(0)
+		if c.Precision.Valid {
+			precision = c.Precision.Int64
+		}
+		if c.Scale.Valid {
+			scale = c.Scale.Int64
+		}
+		maxVal := strconv.FormatFloat(math.Pow10(int(precision-scale)), 'f', -1, 64)
+		return config.ColumnDef{Type: "float/uniform", MinVal: "0", MaxVal: maxVal, Format: fmt.Sprintf("%%.%df", scale)}
+	case "float", "double", "real":
+		return config.ColumnDef{Type: "float/uniform", MinVal: "0", MaxVal: "1000"}
+	case "bit":
+		return config.ColumnDef{Type: "bool"}
+	case "year":
+		return config.ColumnDef{Type: "int/uniform", MinVal: "1901", MaxVal: "2156"}
+	case "date":
+		return config.ColumnDef{Type: "date/uniform"}
+	case "time":
+		return config.ColumnDef{Type: "time/uniform"}
+	case "datetime", "timestamp":
+		return config.ColumnDef{Type: "datetime/uniform", MinVal: "1970-01-02 00:00:00"}
+	case "enum", "set":
+		return config.ColumnDef{Type: "string/oneof", OneOf: oneOf(enumValues(c.ColumnType))}
+	case "json":
+		return config.ColumnDef{Type: "string/oneof", OneOf: "{}"}
+	case "tinytext", "text", "mediumtext", "longtext":
+		return config.ColumnDef{Type: "string/text", Length: int(math.Min(float64(length(c, 255)), maxTextLength))}
+	default: // char, varchar, binary, varbinary, blobs and anything unknown
+		n := int(math.Min(float64(length(c, 10)), maxTextLength))
+		if unique && n >= 36 {
+			return config.ColumnDef{Type: "string/uuid"}
+		}
+		return config.ColumnDef{Type: "string/rand", Length: n}
+	}
+}
+
+// suggestRef picks from the values currently in the referenced column. The Ref replaces them with the values generated
+// for the column when the referenced table is imported in the same run.
+func suggestRef(c Column) config.ColumnDef {
+	ref := c.Ref
+	def := suggestRefValues(c)
+	def.Ref = ref.Table + "." + ref.Column
+	return def
+}
+
+func suggestRefValues(c Column) config.ColumnDef {
+	ref := c.Ref
+	if len(ref.Values) > 0 {
+		gen := "int/oneof"
+		if _, err := strconv.ParseInt(ref.Values[0], 10, 64); err != nil {
+			gen = "string/oneof"
+		}
+		return config.ColumnDef{Type: gen, OneOf: oneOf(ref.Values)}
+	}
+	_, errMin := strconv.ParseInt(ref.Min.String, 10, 64)
+	maxVal, errMax := strconv.ParseInt(ref.Max.String, 10, 64)
+	if ref.Min.Valid && ref.Max.Valid && errMin == nil && errMax == nil {
+		return config.ColumnDef{Type: "int/uniform", MinVal: ref.Min.String, MaxVal: strconv.FormatInt(maxVal+1, 10)}
+	}
+	// Referenced table is empty or keys are not integers, so there is not much to go with.
+	c.Ref = nil
+	return suggestType(&Table{}, c)
+}
+
+func intRange(dataType string, unsigned bool) (string, string) {
+	if dataType == "bigint" {
+		// The spread of generated values has to fit into int64.
+		return "0", strconv.FormatInt(math.MaxInt64, 10)
+	}
+	r := intRanges[dataType]
+	if unsigned {
+		return "0", strconv.FormatInt(2*r[1]+2, 10)
+	}
+	return strconv.FormatInt(r[0], 10), strconv.FormatInt(r[1]+1, 10)
+}
+
+// oneOf formats values for the OneOf field. Values with colons get an explicit weight, so they are parsed correctly.
+func oneOf(values []string) string {
+	opts := make([]string, 0, len(values))
+	for _, v := range values {
+		if strings.Contains(v, ":") {
+			v += ":1"
+		}
+		opts = append(opts, v)
+	}
+	return strings.Join(opts, ";")
+}
+
+func length(c Column, fallback int64) int64 {
+	if c.MaxLength.Valid && c.MaxLength.Int64 > 0 {
+		return c.MaxLength.Int64
+	}
+	return fallback
+}
+
+// enumValues parses values out of a column type like `enum('a','b”c')`.
+func enumValues(columnType string) []string {
+	start := strings.Index(columnType, "(")
+	end := strings.LastIndex(columnType, ")")
+	if start < 0 || end <= start {
+		return nil
+	}
+	var values []string
+	inner := columnType[start+1 : end]
+	for i := 0; i < len(inner); i++ {
+		if inner[i] != '\'' {
+			continue
+		}
+		var sb strings.Builder
+		for i++; i < len(inner); i++ {
+			if inner[i] == '\'' {
+				if i+1 < len(inner) && inner[i+1] == '\'' {
+					sb.WriteByte('\'')
+					i++
+					continue
+				}
+				break
+			}
+			sb.WriteByte(inner[i])
+		}
+		values = append(values, sb.String())
+	}
+	return values
+}
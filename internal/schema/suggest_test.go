@@ -0,0 +1,91 @@
+package schema
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/generators"
+	"github.com/stretchr/testify/assert"
+)
+
+func nullInt(v int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: v, Valid: true}
+}
+
+func nullStr(v string) sql.NullString {
+	return sql.NullString{String: v, Valid: true}
+}
+
+var walletDeposit = &Table{
+	Name:          "account_walletdeposit",
+	AutoIncrement: nullInt(5032),
+	Columns: []Column{
+		{Name: "id", DataType: "int", ColumnType: "int unsigned", Key: "PRI", Extra: "auto_increment"},
+		{Name: "user_id", DataType: "int", ColumnType: "int", Key: "MUL", Ref: &Ref{
+			Table: "auth_user", Column: "id", Min: nullStr("1"), Max: nullStr("2000"),
+		}},
+		{Name: "currency_id", DataType: "int", ColumnType: "int", Key: "MUL", Ref: &Ref{
+			Table: "currency", Column: "id", Values: []string{"1", "2", "5"}, Min: nullStr("1"), Max: nullStr("5"),
+		}},
+		{Name: "txid", DataType: "varchar", ColumnType: "varchar(64)", Key: "UNI", MaxLength: nullInt(64)},
+		{Name: "address", DataType: "varchar", ColumnType: "varchar(35)", Nullable: true, MaxLength: nullInt(35)},
+		{Name: "amount", DataType: "decimal", ColumnType: "decimal(20,8)", Precision: nullInt(20), Scale: nullInt(8)},
+		{Name: "status", DataType: "enum", ColumnType: "enum('new','done','it''s: odd')"},
+		{Name: "instant", DataType: "tinyint", ColumnType: "tinyint(1)"},
+		{Name: "priority", DataType: "smallint", ColumnType: "smallint unsigned"},
+		{Name: "note", DataType: "text", ColumnType: "text", Nullable: true, MaxLength: nullInt(65535)},
+		{Name: "created", DataType: "datetime", ColumnType: "datetime"},
+		{Name: "day", DataType: "date", ColumnType: "date"},
+	},
+}
+
+func TestSuggestTableDef(t *testing.T) {
+	tdef := SuggestTableDef(walletDeposit, 1000, 500)
+	assert.Equal(t, "account_walletdeposit", tdef.TableName)
+	assert.Equal(t, 1000, tdef.TotalRecords)
+	assert.Equal(t, 500, tdef.BatchSize)
+
+	expected := config.Columns{
+		{Name: "id", ColumnDef: config.ColumnDef{Type: "int/incremental-uniform", First: "5032", MinVal: "1", MaxVal: "2"}},
+		{Name: "user_id", ColumnDef: config.ColumnDef{Type: "int/uniform", MinVal: "1", MaxVal: "2001", Ref: "auth_user.id"}},
+		{Name: "currency_id", ColumnDef: config.ColumnDef{Type: "int/oneof", OneOf: "1;2;5", Ref: "currency.id"}},
+		{Name: "txid", ColumnDef: config.ColumnDef{Type: "string/uuid"}},
+		{Name: "address", ColumnDef: config.ColumnDef{Type: "string/rand", Length: 35, Nullable: 0.1}},
+		{Name: "amount", ColumnDef: config.ColumnDef{Type: "float/uniform", MinVal: "0", MaxVal: "1000000000000", Format: "%.8f"}},
+		{Name: "status", ColumnDef: config.ColumnDef{Type: "string/oneof", OneOf: "new;done;it's: odd:1"}},
+		{Name: "instant", ColumnDef: config.ColumnDef{Type: "bool"}},
+		{Name: "priority", ColumnDef: config.ColumnDef{Type: "int/uniform", MinVal: "0", MaxVal: "65536"}},
+		{Name: "note", ColumnDef: config.ColumnDef{Type: "string/text", Length: 1000, Nullable: 0.1}},
+		{Name: "created", ColumnDef: config.ColumnDef{Type: "datetime/uniform", MinVal: "1970-01-02 00:00:00"}},
+		{Name: "day", ColumnDef: config.ColumnDef{Type: "date/uniform"}},
+	}
+	assert.Equal(t, expected, tdef.Columns)
+
+	for _, col := range tdef.Columns {
+		_, err := generators.GetGenerator(col.ColumnDef)
+		assert.NoError(t, err, "column %s", col.Name)
+	}
+}
+
+func TestSuggestIntRanges(t *testing.T) {
+	tests := []struct {
+		dataType   string
+		columnType string
+		minVal     string
+		maxVal     string
+	}{
+		{"tinyint", "tinyint", "-128", "128"},
+		{"tinyint", "tinyint unsigned", "0", "256"},
+		{"mediumint", "mediumint", "-8388608", "8388608"},
+		{"int", "int unsigned", "0", "4294967296"},
+		{"bigint", "bigint", "0", "9223372036854775807"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.columnType, func(t *testing.T) {
+			c := Column{Name: "n", DataType: tt.dataType, ColumnType: tt.columnType}
+			def := SuggestColumnDef(&Table{}, c)
+			assert.Equal(t, config.ColumnDef{Type: "int/uniform", MinVal: tt.minVal, MaxVal: tt.maxVal}, def)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// Package syndi is the public API of syndi for generating synthetic data from Go code, e.g. in integration tests.
+// It exposes the generators, table definitions and the importer, so custom generators can be registered and generated
+// rows written to a database or any other Sink.
+//
+// The API follows semantic versioning, see Version. Everything else in the module is internal and can change at
+// any time.
+package syndi
+
+import (
+	"database/sql"
+
+	"github.com/bitstonks/syndi/internal/config"
+	"github.com/bitstonks/syndi/internal/generators"
+	"github.com/bitstonks/syndi/internal/importer"
+)
+
+// Version of the public API. Until 1.0.0 minor versions can still contain breaking changes.
+const Version = "0.3.0"
+
+type (
+	// Generator produces values of a column.
+	Generator = generators.Generator
+	// Value is a generated value of one of the kinds.
+	Value = generators.Value
+	// Kind is the type of a Value.
+	Kind = generators.Kind
+	// ColumnDef configures the generator of a column.
+	ColumnDef = config.ColumnDef
+	// Column is a named column definition.
+	Column = config.Column
+	// Columns are column definitions in the order they are generated in.
+	Columns = config.Columns
+	// TableDef describes a table and how to generate its data.
+	TableDef = config.TableDef
+	// Vars are values of the ${name} variables in table definitions.
+	Vars = config.Vars
+	// Importer generates the rows of one table and writes them in batches.
+	Importer = importer.Importer
+	// Sink receives batches of generated rows.
+	Sink = importer.Sink
+	// DBSink inserts batches of rows into a database.
+	DBSink = importer.DBSink
+)
+
+// Kinds of values.
+const (
+	Null   = generators.Null
+	Int    = generators.Int
+	Float  = generators.Float
+	String = generators.String
+	Time   = generators.Time
+	Raw    = generators.Raw
+)
+
+// Constructors of values of each kind.
+var (
+	NullValue   = generators.NullValue
+	IntValue    = generators.IntValue
+	FloatValue  = generators.FloatValue
+	StringValue = generators.StringValue
+	TimeValue   = generators.TimeValue
+	RawValue    = generators.RawValue
+	// TimeLayoutValue creates a Time value written in a layout other than the default, e.g. as a date.
+	TimeLayoutValue = generators.TimeLayoutValue
+)
+
+// RegisterGenerator makes a custom generator available for the genType, which can then be used as Type of columns.
+// Registering an existing type replaces its generator.
+func RegisterGenerator(genType string, builder func(ColumnDef) Generator) {
+	generators.RegisterGenerator(genType, builder)
+}
+
+// GetGenerator creates the generator configured by the column definition.
+func GetGenerator(def ColumnDef) (Generator, error) {
+	return generators.GetGenerator(def)
+}
+
+// SetSeed makes generators created after the call deterministic.
+func SetSeed(seed int64) {
+	generators.SetSeed(seed)
+}
+
+// LoadTableDef reads a table definition from a YAML file, replacing variables with vars or environment variables.
+func LoadTableDef(path string, vars Vars) (*TableDef, error) {
+	return config.LoadTableDef(path, vars)
+}
+
+// NewImporter creates an importer inserting the rows of the table into the database. It fails if the generator of a
+// column can't be created from its definition.
+func NewImporter(db *sql.DB, tdef *TableDef) (*Importer, error) {
+	return importer.NewImporter(db, tdef)
+}
+
+// NewDBSink creates a sink inserting batches of rows into the database.
+func NewDBSink(db *sql.DB) *DBSink {
+	return importer.NewDBSink(db)
+}
+
+// NewSinkImporter creates an importer writing the rows of the table to the sink. It fails like NewImporter.
+func NewSinkImporter(sink Sink, tdef *TableDef) (*Importer, error) {
+	return importer.NewSinkImporter(sink, tdef)
+}
@@ -0,0 +1,48 @@
+package syndi_test
+
+import (
+	"fmt"
+
+	"github.com/bitstonks/syndi/pkg/syndi"
+)
+
+type counter struct {
+	n int
+}
+
+func (c *counter) Next() syndi.Value {
+	c.n++
+	return syndi.IntValue(int64(c.n * 10))
+}
+
+type printSink struct{}
+
+func (printSink) WriteBatch(table string, columns []string, rows [][]syndi.Value) error {
+	fmt.Println(table, columns, rows)
+	return nil
+}
+
+func Example() {
+	syndi.RegisterGenerator("int/tens", func(syndi.ColumnDef) syndi.Generator {
+		return &counter{}
+	})
+	tdef := &syndi.TableDef{
+		TableName:    "orders",
+		TotalRecords: 3,
+		BatchSize:    2,
+		Columns: syndi.Columns{
+			{Name: "id", ColumnDef: syndi.ColumnDef{Type: "int/tens"}},
+			{Name: "status", ColumnDef: syndi.ColumnDef{Type: "string/oneof", OneOf: "new"}},
+		},
+	}
+	im, err := syndi.NewSinkImporter(printSink{}, tdef)
+	if err != nil {
+		panic(err)
+	}
+	err = im.Import()
+	fmt.Println(err)
+	// Output:
+	// orders [id status] [[10 'new'] [20 'new']]
+	// orders [id status] [[30 'new']]
+	// <nil>
+}